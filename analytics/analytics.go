@@ -0,0 +1,141 @@
+// Package analytics counts unique daily post views without storing who
+// viewed them.
+package analytics
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go_api/types"
+)
+
+// botMarkers are user agent fragments of crawlers, which aren't readers.
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests"}
+
+type viewKey struct {
+	postID int
+	day    time.Time
+}
+
+// Counter deduplicates views per visitor and day and buffers the counts in
+// memory until they are drained into the database.
+//
+// Visitors are identified by a hash of their IP address and user agent
+// salted with a random value that is replaced every day. The salt is never
+// stored, so hashes can't be linked across days or traced back to an IP.
+type Counter struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	day     time.Time
+	salt    []byte
+	seen    map[string]bool
+	pending map[viewKey]int
+}
+
+func NewCounter() *Counter {
+	return NewCounterWithClock(time.Now)
+}
+
+// NewCounterWithClock is NewCounter with a custom clock, for tests.
+func NewCounterWithClock(now func() time.Time) *Counter {
+	return &Counter{
+		now:     now,
+		pending: map[viewKey]int{},
+	}
+}
+
+// Record counts a view of the post unless the visitor already viewed it
+// today or looks like a bot. It reports whether the view was counted.
+func (c *Counter) Record(postID int, ip, userAgent string) bool {
+	if IsBot(userAgent) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	day := truncateDay(c.now())
+	if !day.Equal(c.day) {
+		c.rotate(day)
+	}
+
+	visitor := c.visitorHash(ip, userAgent)
+	seenKey := fmt.Sprintf("%d:%s", postID, visitor)
+	if c.seen[seenKey] {
+		return false
+	}
+	c.seen[seenKey] = true
+	c.pending[viewKey{postID: postID, day: day}]++
+
+	return true
+}
+
+// Drain returns the buffered counts and empties the buffer.
+func (c *Counter) Drain() []*types.PostViews {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	views := make([]*types.PostViews, 0, len(c.pending))
+	for key, count := range c.pending {
+		views = append(views, &types.PostViews{PostID: key.postID, Day: key.day, Views: count})
+	}
+	c.pending = map[viewKey]int{}
+
+	return views
+}
+
+// Requeue puts drained counts back, after a failed flush.
+func (c *Counter) Requeue(views []*types.PostViews) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, v := range views {
+		c.pending[viewKey{postID: v.PostID, day: v.Day}] += v.Views
+	}
+}
+
+// IsBot reports whether the user agent belongs to a crawler or script.
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// rotate starts a new day with a fresh salt, forgetting yesterday's
+// visitors.
+func (c *Counter) rotate(day time.Time) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+
+	c.day = day
+	c.salt = salt
+	c.seen = map[string]bool{}
+}
+
+func (c *Counter) visitorHash(ip, userAgent string) string {
+	h := sha256.New()
+	h.Write(c.salt)
+	h.Write([]byte(ip))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,73 @@
+package analytics
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma separated list of proxy addresses and
+// CIDR ranges, such as "127.0.0.1, 10.0.0.0/8". Invalid entries are skipped.
+func ParseTrustedProxies(list string) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// ClientIP returns the address of the visitor who sent r. X-Forwarded-For
+// can be set by anyone, so it is only believed when the request comes from a
+// trusted proxy; the visitor is then the last hop no trusted proxy added.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	if !isTrusted(client, trusted) {
+		return client
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		client = hop
+		if !isTrusted(hop, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// FromTrustedProxy reports whether r came straight from a trusted proxy, so
+// that the X-Forwarded-* headers it set can be believed.
+func FromTrustedProxy(r *http.Request, trusted []netip.Prefix) bool {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	return isTrusted(client, trusted)
+}
+
+func isTrusted(address string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sparkline returns the points attribute of an SVG polyline that plots the
+// values left to right in a width by height box, with the largest value
+// touching the top.
+func Sparkline(values []int, width, height float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	highest := 0
+	for _, v := range values {
+		if v > highest {
+			highest = v
+		}
+	}
+
+	step := 0.0
+	if len(values) > 1 {
+		step = width / float64(len(values)-1)
+	}
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		y := height
+		if highest > 0 {
+			y = height - float64(v)/float64(highest)*height
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, y)
+	}
+
+	return strings.Join(points, " ")
+}
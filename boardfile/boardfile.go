@@ -0,0 +1,245 @@
+// Package boardfile reads and writes whole boards as files: this site's own
+// versioned JSON, CSV, and the JSON export of a Trello board.
+package boardfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/types"
+)
+
+// Version is written to every JSON export. Files with another version are
+// refused on import rather than half understood.
+const Version = 1
+
+const dateLayout = "2006-01-02"
+
+// File is the format-independent description of a board.
+type File struct {
+	Version int       `json:"version"`
+	Name    string    `json:"name"`
+	Labels  []*Label  `json:"labels"`
+	Columns []*Column `json:"columns"`
+}
+
+type Label struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type Column struct {
+	Name  string  `json:"name"`
+	Cards []*Card `json:"cards"`
+}
+
+// Card is a card with its sub-cards nested in Cards. Labels refer to the
+// board's labels by name.
+type Card struct {
+	Name     string   `json:"name"`
+	Content  string   `json:"content,omitempty"`
+	Done     bool     `json:"done,omitempty"`
+	DueOn    string   `json:"dueOn,omitempty"`
+	Priority string   `json:"priority,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	Cards    []*Card  `json:"cards,omitempty"`
+}
+
+// FromBoard describes a board loaded with its columns, nested cards and
+// card labels.
+func FromBoard(board *types.Board, labels []*types.Label) *File {
+	file := &File{Version: Version, Name: board.Name, Labels: []*Label{}, Columns: []*Column{}}
+	for _, label := range labels {
+		file.Labels = append(file.Labels, &Label{Name: label.Name, Color: label.Color})
+	}
+	for _, column := range board.Columns {
+		file.Columns = append(file.Columns, &Column{Name: column.Name, Cards: fromCards(column.Cards)})
+	}
+	return file
+}
+
+func fromCards(cards []*types.Card) []*Card {
+	result := []*Card{}
+	for _, card := range cards {
+		exported := &Card{
+			Name:    card.Name,
+			Content: card.Content,
+			Done:    card.Done,
+			Cards:   fromCards(card.Children),
+		}
+		if card.DueOn != nil {
+			exported.DueOn = card.DueOn.Format(dateLayout)
+		}
+		if card.Priority != types.PriorityNone {
+			exported.Priority = string(card.Priority)
+		}
+		for _, label := range card.Labels {
+			exported.Labels = append(exported.Labels, label.Name)
+		}
+		result = append(result, exported)
+	}
+	return result
+}
+
+// ParseJSON reads a file written by JSON.
+func ParseJSON(r io.Reader) (*File, error) {
+	file := new(File)
+	if err := json.NewDecoder(r).Decode(file); err != nil {
+		return nil, fmt.Errorf("invalid board file: %w", err)
+	}
+	if file.Version != Version {
+		return nil, fmt.Errorf("unsupported board file version %d", file.Version)
+	}
+	return file, nil
+}
+
+func (f *File) JSON() ([]byte, error) {
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// CSV writes one row per card, parents before their sub-cards. Cards are
+// numbered in file order and sub-cards name their parent's number.
+func (f *File) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"column", "id", "parent", "name", "content", "done", "due_on", "priority", "labels"}); err != nil {
+		return nil, err
+	}
+
+	id := 0
+	var writeCards func(column string, parent string, cards []*Card) error
+	writeCards = func(column string, parent string, cards []*Card) error {
+		for _, card := range cards {
+			id++
+			row := []string{
+				column,
+				strconv.Itoa(id),
+				parent,
+				card.Name,
+				card.Content,
+				strconv.FormatBool(card.Done),
+				card.DueOn,
+				card.Priority,
+				strings.Join(card.Labels, ";"),
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+			if err := writeCards(column, strconv.Itoa(id), card.Cards); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, column := range f.Columns {
+		if err := writeCards(column.Name, "", column.Cards); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// CardCount counts the cards of the file, sub-cards included.
+func (f *File) CardCount() int {
+	count := 0
+	for _, column := range f.Columns {
+		count += countCards(column.Cards)
+	}
+	return count
+}
+
+func countCards(cards []*Card) int {
+	count := len(cards)
+	for _, card := range cards {
+		count += countCards(card.Cards)
+	}
+	return count
+}
+
+// Board validates the file and turns it into a board ready to be stored,
+// with its labels. Cards carry their labels by name only.
+func (f *File) Board() (*types.Board, []*types.Label, error) {
+	board, err := types.NewBoard(f.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(f.Columns) == 0 {
+		return nil, nil, fmt.Errorf("Board %q has no columns.", f.Name)
+	}
+
+	labels := []*types.Label{}
+	names := map[string]bool{}
+	for _, label := range f.Labels {
+		boardLabel, err := types.NewLabel(0, label.Name, label.Color)
+		if err != nil {
+			return nil, nil, err
+		}
+		if names[boardLabel.Name] {
+			return nil, nil, fmt.Errorf("Label %q already exists.", boardLabel.Name)
+		}
+		names[boardLabel.Name] = true
+		labels = append(labels, boardLabel)
+	}
+
+	board.Columns = []*types.Column{}
+	for i, column := range f.Columns {
+		boardColumn, err := types.NewColumn(0, column.Name, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if boardColumn.Cards, err = toCards(column.Cards, names); err != nil {
+			return nil, nil, err
+		}
+		board.Columns = append(board.Columns, boardColumn)
+	}
+
+	return board, labels, nil
+}
+
+func toCards(cards []*Card, labels map[string]bool) ([]*types.Card, error) {
+	result := []*types.Card{}
+	for _, card := range cards {
+		boardCard, err := types.NewCard(0, card.Name, card.Content, nil)
+		if err != nil {
+			return nil, err
+		}
+		boardCard.Done = card.Done
+
+		if card.DueOn != "" {
+			dueOn, err := time.ParseInLocation(dateLayout, card.DueOn, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date %q on card %q", card.DueOn, card.Name)
+			}
+			boardCard.DueOn = &dueOn
+		}
+
+		if card.Priority != "" {
+			boardCard.Priority = types.CardPriority(card.Priority)
+			if !boardCard.Priority.Valid() {
+				return nil, fmt.Errorf("invalid priority %q on card %q", card.Priority, card.Name)
+			}
+		}
+
+		for _, name := range card.Labels {
+			if !labels[name] {
+				return nil, fmt.Errorf("unknown label %q on card %q", name, card.Name)
+			}
+			boardCard.Labels = append(boardCard.Labels, &types.Label{Name: name})
+		}
+
+		if boardCard.Children, err = toCards(card.Cards, labels); err != nil {
+			return nil, err
+		}
+		result = append(result, boardCard)
+	}
+	return result, nil
+}
@@ -0,0 +1,205 @@
+package boardfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go_api/types"
+)
+
+// trelloColors maps Trello's label colors onto the label palette. Shades
+// such as "green_dark" use their base color.
+var trelloColors = map[string]string{
+	"green":  "#61bd4f",
+	"yellow": "#f2d600",
+	"orange": "#ff9f1a",
+	"red":    "#eb5a46",
+	"purple": "#c377e0",
+	"blue":   "#0079bf",
+	"sky":    "#00c2e0",
+	"lime":   "#61bd4f",
+	"pink":   "#c377e0",
+	"black":  "#344563",
+}
+
+const defaultTrelloColor = "#344563"
+
+type trelloBoard struct {
+	Name       string             `json:"name"`
+	Lists      []*trelloList      `json:"lists"`
+	Cards      []*trelloCard      `json:"cards"`
+	Labels     []*trelloLabel     `json:"labels"`
+	Checklists []*trelloChecklist `json:"checklists"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Desc     string   `json:"desc"`
+	IDList   string   `json:"idList"`
+	Closed   bool     `json:"closed"`
+	Pos      float64  `json:"pos"`
+	Due      *string  `json:"due"`
+	IDLabels []string `json:"idLabels"`
+}
+
+type trelloLabel struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Color *string `json:"color"`
+}
+
+type trelloChecklist struct {
+	IDCard     string             `json:"idCard"`
+	Pos        float64            `json:"pos"`
+	CheckItems []*trelloCheckItem `json:"checkItems"`
+}
+
+type trelloCheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+// ParseTrello reads the JSON export of a Trello board. Open lists become
+// columns and open cards keep their order; checklist items become sub-cards.
+// Names longer than this site allows are shortened, with a card's full name
+// kept at the top of its content.
+func ParseTrello(r io.Reader) (*File, error) {
+	trello := new(trelloBoard)
+	if err := json.NewDecoder(r).Decode(trello); err != nil {
+		return nil, fmt.Errorf("invalid Trello export: %w", err)
+	}
+	if len(trello.Lists) == 0 {
+		return nil, fmt.Errorf("invalid Trello export: no lists found")
+	}
+
+	file := &File{
+		Version: Version,
+		Name:    shorten(trello.Name, types.MaxBoardNameLength),
+		Labels:  []*Label{},
+		Columns: []*Column{},
+	}
+
+	// Trello allows several labels with the same name; they are merged.
+	labelNames := map[string]string{}
+	seen := map[string]bool{}
+	for _, label := range trello.Labels {
+		color := defaultTrelloColor
+		if label.Color != nil {
+			base, _, _ := strings.Cut(*label.Color, "_")
+			if mapped, ok := trelloColors[base]; ok {
+				color = mapped
+			}
+		}
+
+		name := strings.TrimSpace(label.Name)
+		if name == "" && label.Color != nil {
+			name = *label.Color
+		}
+		if name == "" {
+			name = "label"
+		}
+		name = shorten(name, types.MaxLabelNameLength)
+
+		labelNames[label.ID] = name
+		if !seen[name] {
+			seen[name] = true
+			file.Labels = append(file.Labels, &Label{Name: name, Color: color})
+		}
+	}
+
+	checklists := map[string][]*trelloChecklist{}
+	for _, checklist := range trello.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	sort.SliceStable(trello.Lists, func(i, j int) bool { return trello.Lists[i].Pos < trello.Lists[j].Pos })
+	sort.SliceStable(trello.Cards, func(i, j int) bool { return trello.Cards[i].Pos < trello.Cards[j].Pos })
+
+	columns := map[string]*Column{}
+	for _, list := range trello.Lists {
+		if list.Closed {
+			continue
+		}
+		column := &Column{Name: shorten(list.Name, types.MaxColumnNameLength), Cards: []*Card{}}
+		columns[list.ID] = column
+		file.Columns = append(file.Columns, column)
+	}
+
+	for _, trelloCard := range trello.Cards {
+		column, ok := columns[trelloCard.IDList]
+		if trelloCard.Closed || !ok {
+			continue
+		}
+
+		card := &Card{
+			Name:    shorten(trelloCard.Name, types.MaxCardNameLength),
+			Content: strings.TrimSpace(trelloCard.Desc),
+		}
+		if name := strings.TrimSpace(trelloCard.Name); card.Name != name {
+			card.Content = strings.TrimSpace(name + "\n\n" + card.Content)
+		}
+
+		if trelloCard.Due != nil {
+			due, err := time.Parse(time.RFC3339, *trelloCard.Due)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date %q on card %q", *trelloCard.Due, trelloCard.Name)
+			}
+			card.DueOn = due.UTC().Format(dateLayout)
+		}
+
+		for _, id := range trelloCard.IDLabels {
+			if name, ok := labelNames[id]; ok && !contains(card.Labels, name) {
+				card.Labels = append(card.Labels, name)
+			}
+		}
+
+		cardChecklists := checklists[trelloCard.ID]
+		sort.SliceStable(cardChecklists, func(i, j int) bool { return cardChecklists[i].Pos < cardChecklists[j].Pos })
+		for _, checklist := range cardChecklists {
+			items := checklist.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				card.Cards = append(card.Cards, &Card{
+					Name: shorten(item.Name, types.MaxCardNameLength),
+					Done: item.State == "complete",
+				})
+			}
+		}
+
+		column.Cards = append(column.Cards, card)
+	}
+
+	return file, nil
+}
+
+// shorten trims s and cuts it to at most max runes.
+func shorten(s string, max int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max]))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
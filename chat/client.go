@@ -33,10 +33,11 @@ var upgrader = websocket.Upgrader{
 }
 
 type Client struct {
-	hub   *Hub
-	conn  *websocket.Conn
-	send  chan []byte
-	store database.Methods
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	store  database.Methods
+	member Member
 }
 
 type MessageData struct {
@@ -64,6 +65,9 @@ func (c *Client) readPump() {
 		}
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
 		c.hub.broadcast <- message
+		if c.hub.onMessage != nil {
+			go c.hub.onMessage(c.member, message)
+		}
 	}
 }
 
@@ -142,13 +146,15 @@ func (c *Client) writePump(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// ServeWs upgrades the request and joins the connection to the chat as
+// member, the logged in user sending from it or a zero Member.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, member Member) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), member: member}
 	client.hub.register <- client
 
 	go client.writePump(w, r)
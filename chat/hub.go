@@ -5,14 +5,19 @@ type Hub struct {
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
+	// onMessage, when set, sees every message sent to the chat along with
+	// its sender, whose ID is 0 for visitors who are not logged in. It runs
+	// on its own goroutine so a slow callback cannot hold up the chat.
+	onMessage func(sender Member, message []byte)
 }
 
-func NewHub() *Hub {
+func NewHub(onMessage func(sender Member, message []byte)) *Hub {
 	return &Hub{
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
+		onMessage:  onMessage,
 	}
 }
 
@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Member is a person connected to a room, as shown in its presence list.
+// Anonymous visitors have ID 0.
+type Member struct {
+	ID       int
+	Name     string
+	ImageURL string
+	// Role picks the variant of a PublishByRole message the member gets.
+	Role string
+}
+
+// Initials are shown in place of an avatar image.
+func (m Member) Initials() string {
+	initials := []rune{}
+	for _, word := range strings.Fields(m.Name) {
+		initials = append(initials, []rune(word)[0])
+		if len(initials) == 2 {
+			break
+		}
+	}
+	return strings.ToUpper(string(initials))
+}
+
+type roomMessage struct {
+	room    string
+	message []byte
+	// byRole, when set, replaces message with one per member role.
+	byRole map[string][]byte
+}
+
+// RoomHub fans pre-rendered messages out to the clients subscribed to one
+// room, e.g. everyone looking at the same workspace board. Unlike Hub it
+// never reads messages from clients.
+type RoomHub struct {
+	rooms      map[string]map[*RoomClient]bool
+	publish    chan roomMessage
+	register   chan *RoomClient
+	unregister chan *RoomClient
+	// presence renders the message sent to a room whenever someone joins
+	// or leaves it. It may be nil.
+	presence func(room string, members []Member) []byte
+}
+
+type RoomClient struct {
+	hub    *RoomHub
+	room   string
+	member Member
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+func NewRoomHub(presence func(room string, members []Member) []byte) *RoomHub {
+	return &RoomHub{
+		rooms:      make(map[string]map[*RoomClient]bool),
+		publish:    make(chan roomMessage),
+		register:   make(chan *RoomClient),
+		unregister: make(chan *RoomClient),
+		presence:   presence,
+	}
+}
+
+func (h *RoomHub) Run() {
+	for {
+		select {
+		case client := <-h.register:
+			if h.rooms[client.room] == nil {
+				h.rooms[client.room] = make(map[*RoomClient]bool)
+			}
+			h.rooms[client.room][client] = true
+			h.announce(client.room)
+		case client := <-h.unregister:
+			if _, ok := h.rooms[client.room][client]; ok {
+				h.remove(client)
+				h.announce(client.room)
+			}
+		case message := <-h.publish:
+			if message.byRole != nil {
+				h.sendByRole(message.room, message.byRole)
+			} else {
+				h.send(message.room, message.message)
+			}
+		}
+	}
+}
+
+// Publish sends message to every client in room.
+func (h *RoomHub) Publish(room string, message []byte) {
+	h.publish <- roomMessage{room: room, message: message}
+}
+
+// PublishByRole sends every client in room the message for its member's
+// role, e.g. a board with or without its editing controls. Members whose
+// role has no message get nothing.
+func (h *RoomHub) PublishByRole(room string, messages map[string][]byte) {
+	h.publish <- roomMessage{room: room, byRole: messages}
+}
+
+func (h *RoomHub) send(room string, message []byte) {
+	for client := range h.rooms[room] {
+		h.deliver(client, message)
+	}
+}
+
+func (h *RoomHub) sendByRole(room string, messages map[string][]byte) {
+	for client := range h.rooms[room] {
+		if message, ok := messages[client.member.Role]; ok {
+			h.deliver(client, message)
+		}
+	}
+}
+
+// deliver queues message for client, dropping clients too slow to keep up.
+func (h *RoomHub) deliver(client *RoomClient, message []byte) {
+	select {
+	case client.send <- message:
+	default:
+		h.remove(client)
+	}
+}
+
+func (h *RoomHub) remove(client *RoomClient) {
+	delete(h.rooms[client.room], client)
+	close(client.send)
+	if len(h.rooms[client.room]) == 0 {
+		delete(h.rooms, client.room)
+	}
+}
+
+func (h *RoomHub) announce(room string) {
+	if h.presence == nil || len(h.rooms[room]) == 0 {
+		return
+	}
+	h.send(room, h.presence(room, h.members(room)))
+}
+
+// members lists everyone in room once, however many tabs they have open.
+func (h *RoomHub) members(room string) []Member {
+	members := []Member{}
+	seen := map[int]bool{}
+	for client := range h.rooms[room] {
+		if seen[client.member.ID] {
+			continue
+		}
+		seen[client.member.ID] = true
+		members = append(members, client.member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Name != members[j].Name {
+			return members[i].Name < members[j].Name
+		}
+		return members[i].ID < members[j].ID
+	})
+	return members
+}
+
+// readPump only keeps the connection alive; anything the browser sends is
+// dropped.
+func (c *RoomClient) readPump() {
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("error: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func (c *RoomClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Println(err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ServeRoom upgrades the request and subscribes the connection to room.
+func ServeRoom(hub *RoomHub, w http.ResponseWriter, r *http.Request, room string, member Member) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	client := &RoomClient{hub: hub, room: room, member: member, conn: conn, send: make(chan []byte, 256)}
+	client.hub.register <- client
+
+	go client.writePump()
+	go client.readPump()
+}
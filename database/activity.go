@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+
+	"go_api/types"
+)
+
+const insertCardEventQuery = `INSERT INTO card_events
+(card_id, user_id, kind, from_value, to_value, body, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+// GetCardEvents returns the activity stream of a card, oldest first.
+func (s *DbConnection) GetCardEvents(cardID int) ([]*types.CardEvent, error) {
+	query := `SELECT e.id, e.card_id, e.user_id, COALESCE(u.first_name || ' ' || u.last_name, ''),
+	e.kind, e.from_value, e.to_value, e.body, e.created_at
+	FROM card_events e LEFT JOIN users u ON e.user_id = u.id
+	WHERE e.card_id = $1 ORDER BY e.created_at, e.id`
+
+	rows, err := s.DB.Query(query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*types.CardEvent{}
+	for rows.Next() {
+		event, err := scanIntoCardEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *DbConnection) CreateCardComment(event *types.CardEvent) error {
+	return s.DB.QueryRow(
+		insertCardEventQuery,
+		event.CardID,
+		event.UserID,
+		event.Kind,
+		event.From,
+		event.To,
+		event.Body,
+		event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+// insertCardEvent records a change as part of the transaction making it.
+func insertCardEvent(tx *sql.Tx, event *types.CardEvent) error {
+	return tx.QueryRow(
+		insertCardEventQuery,
+		event.CardID,
+		event.UserID,
+		event.Kind,
+		event.From,
+		event.To,
+		event.Body,
+		event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+func scanIntoCardEvent(rows *sql.Rows) (*types.CardEvent, error) {
+	event := new(types.CardEvent)
+	err := rows.Scan(
+		&event.ID,
+		&event.CardID,
+		&event.UserID,
+		&event.Author,
+		&event.Kind,
+		&event.From,
+		&event.To,
+		&event.Body,
+		&event.CreatedAt,
+	)
+	return event, err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"time"
+
+	"go_api/types"
+)
+
+// AddPostViews adds a batch of buffered view counts in one transaction.
+func (s *DbConnection) AddPostViews(views []*types.PostViews) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Posts deleted since the views were counted are skipped.
+	query := `INSERT INTO post_views (post_id, day, views)
+	SELECT id, $2, $3 FROM posts WHERE id = $1
+	ON CONFLICT (post_id, day) DO UPDATE SET views = post_views.views + EXCLUDED.views`
+
+	for _, v := range views {
+		if _, err := tx.Exec(query, v.PostID, v.Day, v.Views); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTopPosts returns the most viewed posts since the given day.
+func (s *DbConnection) GetTopPosts(since time.Time, limit int) ([]*types.PostViewStats, error) {
+	rows, err := s.DB.Query(`SELECT p.id, p.name, p.slug, SUM(v.views) AS total
+	FROM post_views v JOIN posts p ON v.post_id = p.id
+	WHERE v.day >= $1
+	GROUP BY p.id, p.name, p.slug
+	ORDER BY total DESC, p.id DESC LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*types.PostViewStats{}
+	for rows.Next() {
+		stat := new(types.PostViewStats)
+		if err := rows.Scan(&stat.PostID, &stat.Name, &stat.Slug, &stat.Views); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetDailyViews returns total views per day from since until today, with
+// zero for days without views.
+func (s *DbConnection) GetDailyViews(since time.Time) ([]*types.DailyViews, error) {
+	rows, err := s.DB.Query(`SELECT d.day, COALESCE(SUM(v.views), 0)
+	FROM generate_series($1::date, (now() AT TIME ZONE 'utc')::date, interval '1 day') AS d(day)
+	LEFT JOIN post_views v ON v.day = d.day
+	GROUP BY d.day ORDER BY d.day`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*types.DailyViews{}
+	for rows.Next() {
+		day := new(types.DailyViews)
+		if err := rows.Scan(&day.Day, &day.Views); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_api/types"
+
+	_ "github.com/lib/pq"
+)
+
+const getAttachmentQuery = "SELECT a.id, a.post_id, a.card_id, a.blob_key, a.file_name, a.content_type, a.size, a.created_at FROM attachments a "
+
+var attachmentOwnerColumns = map[types.AttachmentKind]string{
+	types.AttachmentPost: "a.post_id",
+	types.AttachmentCard: "a.card_id",
+}
+
+func (s *DbConnection) CreateAttachment(attachment *types.Attachment) error {
+	query := `insert into attachments 
+	(post_id, card_id, blob_key, file_name, content_type, size, created_at)
+	values ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	return s.DB.QueryRow(
+		query,
+		attachment.PostID,
+		attachment.CardID,
+		attachment.Key,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.CreatedAt,
+	).Scan(&attachment.ID)
+}
+
+func (s *DbConnection) GetAttachments(kind types.AttachmentKind, ownerID int) ([]*types.Attachment, error) {
+	column, ok := attachmentOwnerColumns[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown attachment kind %s", kind)
+	}
+
+	rows, err := s.DB.Query(getAttachmentQuery+"WHERE "+column+" = $1 ORDER BY a.created_at", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*types.Attachment{}
+	for rows.Next() {
+		attachment, err := scanIntoAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (s *DbConnection) GetAttachment(id int) (*types.Attachment, error) {
+	rows, err := s.DB.Query(getAttachmentQuery+"WHERE a.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		return scanIntoAttachment(rows)
+	}
+
+	return nil, fmt.Errorf("attachment %d not found", id)
+}
+
+func (s *DbConnection) DeleteAttachment(id int) error {
+	deleteQuery := `DELETE FROM attachments WHERE id = $1 RETURNING id`
+
+	var attachmentID int
+	err := s.DB.QueryRow(deleteQuery, id).Scan(&attachmentID)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("attachment with id %d not found", id)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanIntoAttachment(rows *sql.Rows) (*types.Attachment, error) {
+	attachment := new(types.Attachment)
+	err := rows.Scan(
+		&attachment.ID,
+		&attachment.PostID,
+		&attachment.CardID,
+		&attachment.Key,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+	)
+	return attachment, err
+}
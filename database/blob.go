@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"go_api/types"
+
+	"github.com/lib/pq"
+)
+
+// CreateBlob registers a blob and calls put to store its data when the key
+// was not registered yet. Registering a key that already exists only
+// refreshes its updated_at, which keeps a blob that is about to be reused out
+// of reach of the garbage collector. The row stays locked until put returns,
+// so the collector cannot delete the same key in between; when put fails
+// nothing is registered.
+func (s *DbConnection) CreateBlob(blob *types.Blob, put func() error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// xmax is only set on a row that already existed and was updated.
+	query := `insert into blobs 
+	(key, sha256, size, content_type, ref_count, created_at, updated_at)
+	values ($1, $2, $3, $4, 0, $5, $6)
+	ON CONFLICT (key) DO UPDATE SET updated_at = EXCLUDED.updated_at
+	RETURNING xmax = 0`
+
+	var inserted bool
+	err = tx.QueryRow(
+		query,
+		blob.Key,
+		blob.SHA256,
+		blob.Size,
+		blob.ContentType,
+		blob.CreatedAt,
+		blob.UpdatedAt).Scan(&inserted)
+	if err != nil {
+		return err
+	}
+
+	if inserted {
+		if err := put(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLiveBlobKeys returns the keys that are still referenced, or were
+// registered or released too recently to be collected.
+func (s *DbConnection) GetLiveBlobKeys(cutoff time.Time) (map[string]bool, error) {
+	rows, err := s.DB.Query(`SELECT key FROM blobs WHERE ref_count > 0 OR updated_at >= $1`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys[key] = true
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteUnreferencedBlob removes the row of a blob nobody references, or
+// that was never registered at all, and calls remove to delete the stored
+// file. It reports whether it did. The row stays locked until remove
+// returns, so a CreateBlob of the same key waits and then stores the file
+// again.
+func (s *DbConnection) DeleteUnreferencedBlob(key string, cutoff time.Time, remove func() error) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// An unregistered file gets a placeholder row to hold the lock on.
+	placeholderQuery := `INSERT INTO blobs (key, ref_count, created_at, updated_at)
+	VALUES ($1, 0, '-infinity', '-infinity') ON CONFLICT (key) DO NOTHING`
+	if _, err := tx.Exec(placeholderQuery, key); err != nil {
+		return false, err
+	}
+
+	var deleted string
+	err = tx.QueryRow(`DELETE FROM blobs WHERE key = $1 AND ref_count <= 0 AND updated_at < $2 RETURNING key`, key, cutoff).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := remove(); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func adjustBlobRefs(tx *sql.Tx, keys []string, delta int) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(
+		`update blobs set ref_count = ref_count + $1, updated_at = $2 where key = ANY($3)`,
+		delta,
+		time.Now().UTC(),
+		pq.Array(keys),
+	)
+	return err
+}
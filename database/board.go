@@ -0,0 +1,439 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_api/lexorank"
+	"go_api/types"
+
+	_ "github.com/lib/pq"
+)
+
+const getBoardQuery = "SELECT b.id, b.name, b.version, b.owner_id, b.team_id, COALESCE(b.share_token, ''), b.created_at, b.updated_at FROM boards b "
+
+// ErrBoardChanged is returned for a write based on an outdated version of a
+// board, e.g. when two people drag cards at the same time.
+var ErrBoardChanged = errors.New("board was changed by someone else")
+
+const getColumnQuery = "SELECT col.id, col.board_id, col.name, col.position, col.created_at, col.updated_at FROM columns col "
+
+// GetBoards lists the boards the user can open, each with the user's role.
+func (s *DbConnection) GetBoards(userID int) ([]*types.Board, error) {
+	query := `SELECT b.id, b.name, b.version, b.owner_id, b.team_id, COALESCE(b.share_token, ''), b.created_at, b.updated_at, a.role
+	FROM boards b JOIN board_access a ON a.board_id = b.id WHERE a.user_id = $1 ORDER BY b.name, b.id`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := []*types.Board{}
+	for rows.Next() {
+		var role types.MemberRole
+		board, err := scanIntoBoard(rows, &role)
+		if err != nil {
+			return nil, err
+		}
+		board.Role = role
+		boards = append(boards, board)
+	}
+
+	return boards, rows.Err()
+}
+
+// GetBoard loads a board with its columns and, inside each column, its
+// top-level cards in display order with their sub-cards nested below them.
+func (s *DbConnection) GetBoard(id int) (*types.Board, error) {
+	rows, err := s.DB.Query(getBoardQuery+"WHERE b.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("board %d not found", id)
+	}
+	board, err := scanIntoBoard(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	columnRows, err := s.DB.Query(getColumnQuery+"WHERE col.board_id = $1 ORDER BY col.position, col.id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer columnRows.Close()
+
+	byID := map[int]*types.Column{}
+	for columnRows.Next() {
+		column, err := scanIntoColumn(columnRows)
+		if err != nil {
+			return nil, err
+		}
+		byID[column.ID] = column
+		board.Columns = append(board.Columns, column)
+	}
+	if err := columnRows.Err(); err != nil {
+		return nil, err
+	}
+
+	cardRows, err := s.DB.Query(getCardQuery+"WHERE col.board_id = $1 ORDER BY c.rank, c.id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer cardRows.Close()
+
+	cards := []*types.Card{}
+	for cardRows.Next() {
+		card, err := scanIntoCard(cardRows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err := cardRows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.loadCardLabels(cards...); err != nil {
+		return nil, err
+	}
+
+	// Sub-cards live in their parent's column but only show up as its
+	// checklist.
+	for _, card := range types.NestCards(cards) {
+		if column, ok := byID[card.ColumnID]; ok {
+			column.Cards = append(column.Cards, card)
+		}
+	}
+
+	return board, nil
+}
+
+// CreateBoard inserts the board together with its initial columns. The
+// board must have either an owner or a team.
+func (s *DbConnection) CreateBoard(board *types.Board) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertBoard(tx, board); err != nil {
+		return err
+	}
+
+	for _, column := range board.Columns {
+		column.BoardID = board.ID
+		if err := insertColumn(tx, column); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateColumn appends a column after the last one on its board.
+func (s *DbConnection) CreateColumn(column *types.Column) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockBoard(tx, column.BoardID); err != nil {
+		return err
+	}
+
+	positionQuery := `SELECT COALESCE(MAX(position) + 1, 0) FROM columns WHERE board_id = $1`
+	if err := tx.QueryRow(positionQuery, column.BoardID).Scan(&column.Position); err != nil {
+		return err
+	}
+
+	if err := insertColumn(tx, column); err != nil {
+		return err
+	}
+
+	if err := bumpBoardVersion(tx, column.BoardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MoveCard drops a top-level card into a column between two neighbours by
+// giving it a rank between theirs; its sub-cards only change when the column
+// does, and only then is the move recorded in the card's activity. The move
+// is refused with ErrBoardChanged when the board is no longer at the version
+// the client saw.
+func (s *DbConnection) MoveCard(boardID int, move *types.MoveCardRequest, actorID *int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	version, err := lockBoard(tx, boardID)
+	if err != nil {
+		return err
+	}
+	if version != move.Version {
+		return ErrBoardChanged
+	}
+
+	var columnID int
+	var columnName string
+	err = tx.QueryRow(`SELECT id, name FROM columns WHERE id = $1 AND board_id = $2`, move.ColumnID, boardID).Scan(&columnID, &columnName)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("column %d not found on board %d", move.ColumnID, boardID)
+	} else if err != nil {
+		return err
+	}
+
+	var fromID int
+	var fromName string
+	fromQuery := `SELECT col.id, col.name FROM cards c JOIN columns col ON c.column_id = col.id
+	WHERE c.id = $1 AND c.parent_id IS NULL AND col.board_id = $2`
+	err = tx.QueryRow(fromQuery, move.CardID, boardID).Scan(&fromID, &fromName)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("card %d not found on board %d", move.CardID, boardID)
+	} else if err != nil {
+		return err
+	}
+
+	lower, err := neighbourRank(tx, move.AfterID, columnID)
+	if err != nil {
+		return err
+	}
+	upper, err := neighbourRank(tx, move.BeforeID, columnID)
+	if err != nil {
+		return err
+	}
+	rank, err := lexorank.Between(lower, upper)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	updateQuery := `UPDATE cards SET column_id = $1, rank = $2, updated_at = $3
+	WHERE id = $4 AND parent_id IS NULL AND column_id IN (SELECT id FROM columns WHERE board_id = $5)`
+	result, err := tx.Exec(updateQuery, columnID, rank, now, move.CardID, boardID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("card %d not found on board %d", move.CardID, boardID)
+	}
+
+	// Sub-cards follow their parent into the new column. Within a column
+	// there is nothing to update.
+	subtreeUpdate := subtreeQuery + `UPDATE cards SET column_id = $2, updated_at = $3
+	WHERE id IN (SELECT id FROM subtree WHERE depth > 0) AND column_id <> $2`
+	if _, err := tx.Exec(subtreeUpdate, move.CardID, columnID, now); err != nil {
+		return err
+	}
+
+	if fromID != columnID {
+		if err := insertCardEvent(tx, types.NewCardEvent(move.CardID, actorID, types.EventMoved, fromName, columnName)); err != nil {
+			return err
+		}
+	}
+
+	if err := bumpBoardVersion(tx, boardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// neighbourRank returns the rank of a top-level card next to a drop
+// position. Sub-cards are ranked among their siblings, so they are never a
+// neighbour; like a card that is no longer in the column, one means the
+// client's view is stale.
+func neighbourRank(tx *sql.Tx, cardID *int, columnID int) (string, error) {
+	if cardID == nil {
+		return "", nil
+	}
+
+	var rank string
+	err := tx.QueryRow(`SELECT rank FROM cards WHERE id = $1 AND column_id = $2 AND parent_id IS NULL`, *cardID, columnID).Scan(&rank)
+	if err == sql.ErrNoRows {
+		return "", ErrBoardChanged
+	}
+	return rank, err
+}
+
+// lockBoard serialises writers that change the layout of one board and
+// returns its current version.
+func lockBoard(tx *sql.Tx, boardID int) (int, error) {
+	var version int
+	err := tx.QueryRow(`SELECT version FROM boards WHERE id = $1 FOR UPDATE`, boardID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("board %d not found", boardID)
+	}
+	return version, err
+}
+
+func bumpBoardVersion(tx *sql.Tx, boardID int) error {
+	_, err := tx.Exec(`UPDATE boards SET version = version + 1, updated_at = $1 WHERE id = $2`, time.Now().UTC(), boardID)
+	return err
+}
+
+// ImportBoard stores a new board with its columns, labels and nested cards
+// in one transaction, so a file that fails halfway leaves nothing behind.
+// Cards refer to labels by name and are recorded as created by actorID.
+func (s *DbConnection) ImportBoard(board *types.Board, labels []*types.Label, actorID *int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertBoard(tx, board); err != nil {
+		return err
+	}
+
+	labelIDs := map[string]int{}
+	for _, label := range labels {
+		label.BoardID = board.ID
+		labelQuery := `INSERT INTO labels (board_id, name, color) VALUES ($1, $2, $3) RETURNING id`
+		if err := tx.QueryRow(labelQuery, label.BoardID, label.Name, label.Color).Scan(&label.ID); err != nil {
+			return err
+		}
+		labelIDs[label.Name] = label.ID
+	}
+
+	for _, column := range board.Columns {
+		column.BoardID = board.ID
+		if err := insertColumn(tx, column); err != nil {
+			return err
+		}
+		if err := importCards(tx, column, nil, column.Cards, labelIDs, actorID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// importCards inserts sibling cards in order, each followed by its own
+// sub-cards.
+func importCards(tx *sql.Tx, column *types.Column, parent *int, cards []*types.Card, labelIDs map[string]int, actorID *int) error {
+	rank := ""
+	for _, card := range cards {
+		var err error
+		if rank, err = lexorank.After(rank); err != nil {
+			return err
+		}
+		card.ColumnID = column.ID
+		card.BoardID = column.BoardID
+		card.Parent = parent
+		card.Rank = rank
+
+		query := `INSERT INTO cards
+		(column_id, name, content, rank, parent_id, done, due_on, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+
+		err = tx.QueryRow(
+			query,
+			card.ColumnID,
+			card.Name,
+			card.Content,
+			card.Rank,
+			card.Parent,
+			card.Done,
+			card.DueOn,
+			card.Priority,
+			card.CreatedAt,
+			card.UpdatedAt).Scan(&card.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, label := range card.Labels {
+			labelID, ok := labelIDs[label.Name]
+			if !ok {
+				return fmt.Errorf("label %q not found", label.Name)
+			}
+			if _, err := tx.Exec(`INSERT INTO card_labels (card_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, card.ID, labelID); err != nil {
+				return err
+			}
+		}
+
+		if err := insertCardEvent(tx, types.NewCardEvent(card.ID, actorID, types.EventCreated, "", "")); err != nil {
+			return err
+		}
+
+		if err := importCards(tx, column, &card.ID, card.Children, labelIDs, actorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertBoard(tx *sql.Tx, board *types.Board) error {
+	query := `INSERT INTO boards (name, owner_id, team_id, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return tx.QueryRow(
+		query,
+		board.Name,
+		board.OwnerID,
+		board.TeamID,
+		board.CreatedAt,
+		board.UpdatedAt,
+	).Scan(&board.ID)
+}
+
+func insertColumn(tx *sql.Tx, column *types.Column) error {
+	query := `INSERT INTO columns (board_id, name, position, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return tx.QueryRow(
+		query,
+		column.BoardID,
+		column.Name,
+		column.Position,
+		column.CreatedAt,
+		column.UpdatedAt,
+	).Scan(&column.ID)
+}
+
+// scanIntoBoard reads the columns of getBoardQuery, followed by any extra
+// columns the query selected.
+func scanIntoBoard(rows *sql.Rows, extra ...any) (*types.Board, error) {
+	board := &types.Board{Columns: []*types.Column{}}
+	dest := []any{
+		&board.ID,
+		&board.Name,
+		&board.Version,
+		&board.OwnerID,
+		&board.TeamID,
+		&board.ShareToken,
+		&board.CreatedAt,
+		&board.UpdatedAt,
+	}
+	err := rows.Scan(append(dest, extra...)...)
+	return board, err
+}
+
+func scanIntoColumn(rows *sql.Rows) (*types.Column, error) {
+	column := &types.Column{Cards: []*types.Card{}}
+	err := rows.Scan(
+		&column.ID,
+		&column.BoardID,
+		&column.Name,
+		&column.Position,
+		&column.CreatedAt,
+		&column.UpdatedAt,
+	)
+	return column, err
+}
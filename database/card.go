@@ -2,54 +2,87 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"go_api/lexorank"
 	"go_api/types"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-const getCardQuery = "SELECT c.id, c.name, c.content, c.position, c.type, c.parent_id FROM cards c ORDER BY c.position"
+// subtreeQuery selects the ids of a card and all of its descendants.
+const subtreeQuery = `WITH RECURSIVE subtree AS (
+	SELECT id, 0 AS depth FROM cards WHERE id = $1
+	UNION ALL
+	SELECT c.id, s.depth + 1 FROM cards c JOIN subtree s ON c.parent_id = s.id
+) `
 
-func (s *DbConnection) GetCards() ([]*types.Card, error) {
-	rows, err := s.DB.Query(getCardQuery)
+// ErrCardHasChildren is returned when deleting a card that still has
+// sub-cards without asking for them to be deleted too.
+var ErrCardHasChildren = errors.New("card has sub-cards")
+
+const getCardQuery = `SELECT c.id, c.column_id, col.board_id, c.name, c.content, c.rank, c.parent_id, c.done,
+c.assignee_id, COALESCE(u.first_name || ' ' || u.last_name, ''), c.due_on, c.priority
+FROM cards c JOIN columns col ON c.column_id = col.id LEFT JOIN users u ON c.assignee_id = u.id `
+
+// CreateCard appends the card after its last sibling: the last top-level
+// card of its column, or the last sub-card of its parent. actorID is the
+// user recorded as having created it, nil when unknown.
+func (s *DbConnection) CreateCard(card *types.Card, actorID *int) error {
+	tx, err := s.DB.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	cards := []*types.Card{}
-	for rows.Next() {
-		card, err := scanIntoCard(rows)
-		if err != nil {
-			return nil, err
-		}
-		cards = append(cards, card)
+	err = tx.QueryRow(`SELECT board_id FROM columns WHERE id = $1`, card.ColumnID).Scan(&card.BoardID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("column %d not found", card.ColumnID)
+	} else if err != nil {
+		return err
+	}
+	if _, err := lockBoard(tx, card.BoardID); err != nil {
+		return err
 	}
 
-	return cards, nil
-}
+	var last string
+	lastQuery := `SELECT COALESCE(MAX(rank), '') FROM cards WHERE column_id = $1 AND parent_id IS NOT DISTINCT FROM $2`
+	if err := tx.QueryRow(lastQuery, card.ColumnID, card.Parent).Scan(&last); err != nil {
+		return err
+	}
+	if card.Rank, err = lexorank.After(last); err != nil {
+		return err
+	}
 
-func (s *DbConnection) CreateCard(card *types.Card) error {
 	query := `insert into cards 
-	(name, content, position, type, parent_id, created_at, updated_at)
-	values ($1, $2, $3, $4, $5, $6, $7)`
+	(column_id, name, content, rank, parent_id, created_at, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
 
-	_, err := s.DB.Query(
+	err = tx.QueryRow(
 		query,
+		card.ColumnID,
 		card.Name,
 		card.Content,
-		card.Position,
-		card.CardType,
+		card.Rank,
 		card.Parent,
 		card.CreatedAt,
-		card.UpdatedAt)
+		card.UpdatedAt).Scan(&card.ID)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if err := insertCardEvent(tx, types.NewCardEvent(card.ID, actorID, types.EventCreated, "", "")); err != nil {
+		return err
+	}
+
+	if err := bumpBoardVersion(tx, card.BoardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *DbConnection) GetCard(id int) (*types.Card, error) {
@@ -58,24 +91,61 @@ func (s *DbConnection) GetCard(id int) (*types.Card, error) {
 		return nil, err
 	}
 
-	for rows.Next() {
-		return scanIntoCard(rows)
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("card %d not found", id)
+	}
+	card, err := scanIntoCard(rows)
+	if err != nil {
+		return nil, err
 	}
+	rows.Close()
 
-	return nil, fmt.Errorf("card %d not found", id)
+	if err := s.loadCardLabels(card); err != nil {
+		return nil, err
+	}
+	return card, nil
 }
 
-func (s *DbConnection) UpdateCard(card *types.Card) error {
-	updateQuery := `update cards set name = $1 , content = $2, position = $3, parent_id = $4, updated_at= $5 where id = $6 RETURNING id`
+// UpdateCard saves the card's editable fields and replaces its labels.
+// Labels that belong to another board are ignored. A new name or assignee
+// is recorded in the card's activity as done by actorID.
+func (s *DbConnection) UpdateCard(card *types.Card, actorID *int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	boardID, err := lockCardBoard(tx, card.ID)
+	if err != nil {
+		return err
+	}
+
+	var previousName string
+	var previousAssignee *int
+	err = tx.QueryRow(`SELECT name, assignee_id FROM cards WHERE id = $1 FOR UPDATE`, card.ID).Scan(&previousName, &previousAssignee)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("card %d not found", card.ID)
+	} else if err != nil {
+		return err
+	}
+
+	updateQuery := `update cards set name = $1 , content = $2, assignee_id = $3, due_on = $4, priority = $5, updated_at= $6 where id = $7 RETURNING id`
 
 	var cardId int
-	err := s.DB.QueryRow(
+	err = tx.QueryRow(
 		updateQuery,
 		card.Name,
 		card.Content,
-		card.Position,
-		card.Parent,
-		time.Now(),
+		card.AssigneeID,
+		card.DueOn,
+		card.Priority,
+		time.Now().UTC(),
 		card.ID,
 	).Scan(&cardId)
 
@@ -85,55 +155,194 @@ func (s *DbConnection) UpdateCard(card *types.Card) error {
 		return err
 	}
 
-	return nil
+	if _, err := tx.Exec(`DELETE FROM card_labels WHERE card_id = $1`, card.ID); err != nil {
+		return err
+	}
+
+	labelIDs := make([]int64, len(card.Labels))
+	for i, label := range card.Labels {
+		labelIDs[i] = int64(label.ID)
+	}
+	_, err = tx.Exec(`INSERT INTO card_labels (card_id, label_id)
+	SELECT c.id, l.id FROM cards c
+	JOIN columns col ON c.column_id = col.id
+	JOIN labels l ON l.board_id = col.board_id
+	WHERE c.id = $1 AND l.id = ANY($2)`, card.ID, pq.Array(labelIDs))
+	if err != nil {
+		return err
+	}
+
+	if previousName != card.Name {
+		if err := insertCardEvent(tx, types.NewCardEvent(card.ID, actorID, types.EventRenamed, previousName, card.Name)); err != nil {
+			return err
+		}
+	}
+
+	if !sameUser(previousAssignee, card.AssigneeID) {
+		assignee := ""
+		if card.AssigneeID != nil {
+			nameQuery := `SELECT first_name || ' ' || last_name FROM users WHERE id = $1`
+			if err := tx.QueryRow(nameQuery, *card.AssigneeID).Scan(&assignee); err == sql.ErrNoRows {
+				return fmt.Errorf("user %d not found", *card.AssigneeID)
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := insertCardEvent(tx, types.NewCardEvent(card.ID, actorID, types.EventAssigned, "", assignee)); err != nil {
+			return err
+		}
+	}
 
+	if err := bumpBoardVersion(tx, boardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *DbConnection) DeleteCard(id int) error {
-	deleteQuery := `DELETE FROM cards WHERE id = $1 RETURNING id`
+// GetCardTree loads a card with all of its sub-cards nested below it.
+func (s *DbConnection) GetCardTree(id int) (*types.Card, error) {
+	query := subtreeQuery + getCardQuery + "JOIN subtree t ON t.id = c.id ORDER BY t.depth, c.rank, c.id"
+	rows, err := s.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var cardID int
-	err := s.DB.QueryRow(deleteQuery, id).Scan(&cardID)
+	cards := []*types.Card{}
+	for rows.Next() {
+		card, err := scanIntoCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadCardLabels(cards...); err != nil {
+		return nil, err
+	}
+
+	for _, root := range types.NestCards(cards) {
+		if root.ID == id {
+			return root, nil
+		}
+	}
+	return nil, fmt.Errorf("card %d not found", id)
+}
+
+func (s *DbConnection) ToggleCardDone(id int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	boardID, err := lockCardBoard(tx, id)
+	if err != nil {
+		return err
+	}
+
+	updateQuery := `UPDATE cards SET done = NOT done, updated_at = $1 WHERE id = $2 RETURNING id`
 
+	var cardID int
+	err = tx.QueryRow(updateQuery, time.Now().UTC(), id).Scan(&cardID)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("card with id %d not found", id)
+		return fmt.Errorf("card %d not found", id)
 	} else if err != nil {
 		return err
 	}
 
-	return nil
+	if err := bumpBoardVersion(tx, boardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *DbConnection) ReorderCards(id []string) ([]*types.Card, error) {
-	updateQuery := `update cards set position = $1 where id = $2 RETURNING id`
+// DeleteCard removes a card. A card with sub-cards is only removed together
+// with its whole subtree when cascade is set; otherwise ErrCardHasChildren
+// is returned and nothing changes.
+func (s *DbConnection) DeleteCard(id int, cascade bool) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	for i := range id {
-		var cardId int
-		updateErr := s.DB.QueryRow(
-			updateQuery,
-			i,
-			id[i],
-		).Scan(&cardId)
+	boardID, err := lockCardBoard(tx, id)
+	if err != nil {
+		return err
+	}
 
-		if updateErr != nil {
-			return nil, updateErr
-		}
+	var hasChildren bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM cards WHERE parent_id = $1)`, id).Scan(&hasChildren); err != nil {
+		return err
+	}
+	if hasChildren && !cascade {
+		return ErrCardHasChildren
 	}
-	orderedCards, _ := s.GetCards()
 
-	return orderedCards, nil
+	result, err := tx.Exec(subtreeQuery+`DELETE FROM cards WHERE id IN (SELECT id FROM subtree)`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("card with id %d not found", id)
+	}
+
+	if err := bumpBoardVersion(tx, boardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
 
+// lockCardBoard locks the board a card is on, as lockBoard does, and returns
+// its id. The board is locked before the card is written, in the same order
+// as MoveCard, so the two cannot deadlock.
+func lockCardBoard(tx *sql.Tx, cardID int) (int, error) {
+	var boardID int
+	query := `SELECT col.board_id FROM cards c JOIN columns col ON c.column_id = col.id WHERE c.id = $1`
+	err := tx.QueryRow(query, cardID).Scan(&boardID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("card %d not found", cardID)
+	} else if err != nil {
+		return 0, err
+	}
+
+	if _, err := lockBoard(tx, boardID); err != nil {
+		return 0, err
+	}
+	return boardID, nil
+}
+
+func sameUser(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func scanIntoCard(rows *sql.Rows) (*types.Card, error) {
 	card := new(types.Card)
 	err := rows.Scan(
 		&card.ID,
+		&card.ColumnID,
+		&card.BoardID,
 		&card.Name,
 		&card.Content,
-		&card.Position,
-		&card.CardType,
+		&card.Rank,
 		&card.Parent,
+		&card.Done,
+		&card.AssigneeID,
+		&card.Assignee,
+		&card.DueOn,
+		&card.Priority,
 	)
 	return card, err
 }
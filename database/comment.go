@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_api/types"
+)
+
+const getCommentQuery = `SELECT c.id, c.post_id, c.parent_id, c.user_id, COALESCE(u.first_name || ' ' || u.last_name, ''), c.body, c.status, c.created_at, c.updated_at, p.name, p.slug
+FROM comments c JOIN users u ON c.user_id = u.id JOIN posts p ON c.post_id = p.id `
+
+func (s *DbConnection) CreateComment(comment *types.Comment) error {
+	query := `insert into comments 
+	(post_id, parent_id, user_id, body, status, created_at, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	return s.DB.QueryRow(
+		query,
+		comment.PostID,
+		comment.ParentID,
+		comment.UserID,
+		comment.Body,
+		comment.Status,
+		comment.CreatedAt,
+		comment.UpdatedAt,
+	).Scan(&comment.ID)
+}
+
+func (s *DbConnection) GetComment(id int) (*types.Comment, error) {
+	rows, err := s.DB.Query(getCommentQuery+"WHERE c.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		return scanIntoComment(rows)
+	}
+
+	return nil, fmt.Errorf("comment %d not found", id)
+}
+
+// GetPostComments returns the approved comments on a post, oldest first,
+// plus the viewer's own comments that are still waiting for moderation.
+func (s *DbConnection) GetPostComments(postID int, viewerID *int) ([]*types.Comment, error) {
+	return s.queryComments(getCommentQuery+`WHERE c.post_id = $1
+	AND (c.status = 'approved' OR (c.status = 'pending' AND c.user_id = $2))
+	ORDER BY c.created_at, c.id`, postID, viewerID)
+}
+
+// GetPendingComments returns the moderation queue, oldest first.
+func (s *DbConnection) GetPendingComments() ([]*types.Comment, error) {
+	return s.queryComments(getCommentQuery + `WHERE c.status = 'pending' ORDER BY c.created_at, c.id`)
+}
+
+func (s *DbConnection) UpdateCommentStatus(id int, status types.CommentStatus) error {
+	var commentID int
+	err := s.DB.QueryRow(`UPDATE comments SET status = $1, updated_at = $2 WHERE id = $3 RETURNING id`,
+		status, time.Now().UTC(), id).Scan(&commentID)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("comment %d not found", id)
+	}
+	return err
+}
+
+func (s *DbConnection) DeleteComment(id int) error {
+	var commentID int
+	err := s.DB.QueryRow(`DELETE FROM comments WHERE id = $1 RETURNING id`, id).Scan(&commentID)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("comment with id %d not found", id)
+	}
+	return err
+}
+
+// HasApprovedComments reports whether the user has had a comment approved
+// before, which lets their new comments skip moderation.
+func (s *DbConnection) HasApprovedComments(userID int) (bool, error) {
+	var approved bool
+	err := s.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM comments WHERE user_id = $1 AND status = 'approved')`, userID).Scan(&approved)
+	return approved, err
+}
+
+// CountCommentsSince counts the comments the user wrote after since, for
+// rate limiting. Counting in the database keeps the limit shared between
+// replicas.
+func (s *DbConnection) CountCommentsSince(userID int, since time.Time) (int, error) {
+	var count int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM comments WHERE user_id = $1 AND created_at > $2`, userID, since).Scan(&count)
+	return count, err
+}
+
+func (s *DbConnection) queryComments(query string, args ...any) ([]*types.Comment, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*types.Comment{}
+	for rows.Next() {
+		comment, err := scanIntoComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func scanIntoComment(rows *sql.Rows) (*types.Comment, error) {
+	comment := new(types.Comment)
+	err := rows.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.UserID,
+		&comment.Author,
+		&comment.Body,
+		&comment.Status,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.PostName,
+		&comment.PostSlug,
+	)
+	return comment, err
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_api/types"
+
+	"github.com/lib/pq"
+)
+
+func (s *DbConnection) GetLabels(boardID int) ([]*types.Label, error) {
+	rows, err := s.DB.Query(`SELECT id, board_id, name, color FROM labels WHERE board_id = $1 ORDER BY name`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []*types.Label{}
+	for rows.Next() {
+		label := new(types.Label)
+		if err := rows.Scan(&label.ID, &label.BoardID, &label.Name, &label.Color); err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+func (s *DbConnection) CreateLabel(label *types.Label) error {
+	query := `INSERT INTO labels (board_id, name, color) VALUES ($1, $2, $3)
+	ON CONFLICT (board_id, name) DO NOTHING RETURNING id`
+
+	err := s.DB.QueryRow(query, label.BoardID, label.Name, label.Color).Scan(&label.ID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("Label %q already exists.", label.Name)
+	}
+	return err
+}
+
+// loadCardLabels fills in the labels of the given cards with a single query.
+func (s *DbConnection) loadCardLabels(cards ...*types.Card) error {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(cards))
+	byID := make(map[int]*types.Card, len(cards))
+	for i, card := range cards {
+		ids[i] = int64(card.ID)
+		byID[card.ID] = card
+		card.Labels = []*types.Label{}
+	}
+
+	rows, err := s.DB.Query(`SELECT cl.card_id, l.id, l.board_id, l.name, l.color FROM card_labels cl
+	JOIN labels l ON cl.label_id = l.id WHERE cl.card_id = ANY($1) ORDER BY l.name`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cardID int
+		label := new(types.Label)
+		if err := rows.Scan(&cardID, &label.ID, &label.BoardID, &label.Name, &label.Color); err != nil {
+			return err
+		}
+		if card, ok := byID[cardID]; ok {
+			card.Labels = append(card.Labels, label)
+		}
+	}
+
+	return rows.Err()
+}
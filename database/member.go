@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go_api/types"
+)
+
+// ErrLastTeamAdmin is returned for a change that would leave a team without
+// an admin to manage it.
+var ErrLastTeamAdmin = errors.New("team needs an admin")
+
+// GetBoardRole returns the user's role on the board, or the empty role when
+// the user cannot open it.
+func (s *DbConnection) GetBoardRole(boardID int, userID int) (types.MemberRole, error) {
+	var role types.MemberRole
+	err := s.DB.QueryRow(`SELECT role FROM board_access WHERE board_id = $1 AND user_id = $2`, boardID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetCardBoardRole returns the board of the card and the user's role on it,
+// the empty role when the user cannot open it.
+func (s *DbConnection) GetCardBoardRole(cardID int, userID int) (int, types.MemberRole, error) {
+	query := `SELECT col.board_id, COALESCE(a.role, '') FROM cards c
+	JOIN columns col ON c.column_id = col.id
+	LEFT JOIN board_access a ON a.board_id = col.board_id AND a.user_id = $2
+	WHERE c.id = $1`
+
+	var boardID int
+	var role types.MemberRole
+	err := s.DB.QueryRow(query, cardID, userID).Scan(&boardID, &role)
+	if err == sql.ErrNoRows {
+		return 0, "", fmt.Errorf("card %d not found", cardID)
+	}
+	return boardID, role, err
+}
+
+// GetBoardMembers lists everyone who can open the board with their
+// strongest role. Only users added to the board itself can be removed.
+func (s *DbConnection) GetBoardMembers(boardID int) ([]*types.Member, error) {
+	query := `SELECT u.id, u.first_name, u.last_name, u.email, a.role, bm.user_id IS NOT NULL
+	FROM board_access a JOIN users u ON u.id = a.user_id
+	LEFT JOIN board_members bm ON bm.board_id = a.board_id AND bm.user_id = a.user_id
+	WHERE a.board_id = $1 ORDER BY u.first_name, u.last_name, u.id`
+
+	return s.queryMembers(query, boardID)
+}
+
+// GetBoardUsers returns the users who can open the board, e.g. to assign
+// its cards.
+func (s *DbConnection) GetBoardUsers(boardID int) ([]*types.User, error) {
+	rows, err := s.DB.Query(getUserQuery+`WHERE u.id IN (SELECT user_id FROM board_access WHERE board_id = $1)
+	ORDER BY u.first_name, u.last_name, u.id`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*types.User{}
+	for rows.Next() {
+		user, err := scanIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetBoardMember adds the user to the board, or changes the role they were
+// given on it.
+func (s *DbConnection) SetBoardMember(boardID int, userID int, role types.MemberRole) error {
+	query := `INSERT INTO board_members (board_id, user_id, role) VALUES ($1, $2, $3)
+	ON CONFLICT (board_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	_, err := s.DB.Exec(query, boardID, userID, role)
+	return err
+}
+
+func (s *DbConnection) RemoveBoardMember(boardID int, userID int) error {
+	_, err := s.DB.Exec(`DELETE FROM board_members WHERE board_id = $1 AND user_id = $2`, boardID, userID)
+	return err
+}
+
+// SetBoardShareToken shares the board with anyone who has the token, or
+// stops sharing it when token is empty.
+func (s *DbConnection) SetBoardShareToken(boardID int, token string) error {
+	_, err := s.DB.Exec(`UPDATE boards SET share_token = NULLIF($1, '') WHERE id = $2`, token, boardID)
+	return err
+}
+
+// GetSharedBoard loads the board shared under the token.
+func (s *DbConnection) GetSharedBoard(token string) (*types.Board, error) {
+	var id int
+	err := s.DB.QueryRow(`SELECT id FROM boards WHERE share_token = $1`, token).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("shared board not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.GetBoard(id)
+}
+
+// GetTeams lists the teams the user belongs to, with their members.
+func (s *DbConnection) GetTeams(userID int) ([]*types.Team, error) {
+	query := `SELECT t.id, t.name, tm.role, t.created_at FROM teams t
+	JOIN team_members tm ON tm.team_id = t.id WHERE tm.user_id = $1 ORDER BY t.name, t.id`
+
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []*types.Team{}
+	for rows.Next() {
+		team := &types.Team{Members: []*types.Member{}}
+		if err := rows.Scan(&team.ID, &team.Name, &team.Role, &team.CreatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	memberQuery := `SELECT u.id, u.first_name, u.last_name, u.email, tm.role, TRUE
+	FROM team_members tm JOIN users u ON u.id = tm.user_id
+	WHERE tm.team_id = $1 ORDER BY u.first_name, u.last_name, u.id`
+	for _, team := range teams {
+		if team.Members, err = s.queryMembers(memberQuery, team.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return teams, nil
+}
+
+// CreateTeam inserts the team with its creator as its first admin.
+func (s *DbConnection) CreateTeam(team *types.Team, creatorID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO teams (name, created_at) VALUES ($1, $2) RETURNING id`
+	if err := tx.QueryRow(query, team.Name, team.CreatedAt).Scan(&team.ID); err != nil {
+		return err
+	}
+
+	memberQuery := `INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(memberQuery, team.ID, creatorID, types.MemberAdmin); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTeamRole returns the user's role in the team, or the empty role when
+// the user does not belong to it.
+func (s *DbConnection) GetTeamRole(teamID int, userID int) (types.MemberRole, error) {
+	var role types.MemberRole
+	err := s.DB.QueryRow(`SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2`, teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// SetTeamMember adds the user to the team, or changes their role in it.
+func (s *DbConnection) SetTeamMember(teamID int, userID int, role types.MemberRole) error {
+	query := `INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)
+	ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	return s.changeTeamMembers(teamID, query, teamID, userID, role)
+}
+
+func (s *DbConnection) RemoveTeamMember(teamID int, userID int) error {
+	return s.changeTeamMembers(teamID, `DELETE FROM team_members WHERE team_id = $1 AND user_id = $2`, teamID, userID)
+}
+
+// changeTeamMembers runs a change to the members of a team, refusing it
+// with ErrLastTeamAdmin when no admin would be left. The team row is locked
+// so two admins demoting each other cannot both succeed.
+func (s *DbConnection) changeTeamMembers(teamID int, query string, args ...any) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	if err := tx.QueryRow(`SELECT id FROM teams WHERE id = $1 FOR UPDATE`, teamID).Scan(&id); err == sql.ErrNoRows {
+		return fmt.Errorf("team %d not found", teamID)
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	var admins int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM team_members WHERE team_id = $1 AND role = $2`, teamID, types.MemberAdmin).Scan(&admins); err != nil {
+		return err
+	}
+	if admins == 0 {
+		return ErrLastTeamAdmin
+	}
+
+	return tx.Commit()
+}
+
+func (s *DbConnection) queryMembers(query string, args ...any) ([]*types.Member, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*types.Member{}
+	for rows.Next() {
+		member := new(types.Member)
+		var firstName, lastName string
+		if err := rows.Scan(&member.UserID, &firstName, &lastName, &member.Email, &member.Role, &member.Removable); err != nil {
+			return nil, err
+		}
+		member.Name = firstName + " " + lastName
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
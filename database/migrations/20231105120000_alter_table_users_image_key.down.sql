@@ -0,0 +1,11 @@
+-- image_url pointed at a file under static/uploads, where the local blob
+-- store keeps its files. Avatars uploaded with renditions have no original
+-- stored, so they point at their large rendition instead. Avatars kept in an
+-- S3 bucket are not under static/uploads and cannot be restored this way.
+UPDATE users SET image_key = '/static/uploads/' || CASE
+    WHEN image_key LIKE '%/%' THEN regexp_replace(image_key, '(\.[^./]*)?$', '_large\1')
+    ELSE image_key
+END
+WHERE image_key <> '';
+
+ALTER TABLE users RENAME COLUMN image_key TO image_url;
@@ -0,0 +1,5 @@
+ALTER TABLE users RENAME COLUMN image_url TO image_key;
+
+-- Older rows hold a path such as "../static/uploads/123.png". The local blob
+-- store is rooted at static/uploads, so the file name alone is the key.
+UPDATE users SET image_key = regexp_replace(image_key, '^.*/', '') WHERE image_key LIKE '%/%';
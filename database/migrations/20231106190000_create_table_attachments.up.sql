@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id serial PRIMARY KEY,
+    post_id INT,
+    card_id INT,
+    blob_key VARCHAR(200) NOT NULL,
+    file_name VARCHAR(200) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE CASCADE,
+    CHECK ((post_id IS NULL) <> (card_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_post_id ON attachments (post_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_card_id ON attachments (card_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id VARCHAR(32) PRIMARY KEY,
+    card_id INT NOT NULL,
+    file_name VARCHAR(200) NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    checksum VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    FOREIGN KEY (card_id) REFERENCES cards (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_updated_at ON uploads (updated_at);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS attachments_blob_refs ON attachments;
+
+DROP FUNCTION IF EXISTS attachments_blob_refs();
+
+DROP TABLE IF EXISTS blobs;
@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    key VARCHAR(200) PRIMARY KEY,
+    sha256 CHAR(64),
+    size BIGINT,
+    content_type VARCHAR(100),
+    ref_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_blobs_ref_count ON blobs (ref_count);
+
+-- Attachments keep their blob's reference count up to date themselves, so
+-- rows removed by ON DELETE CASCADE from posts and cards are counted too.
+CREATE OR REPLACE FUNCTION attachments_blob_refs() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('DELETE', 'UPDATE') THEN
+        UPDATE blobs SET ref_count = ref_count - 1, updated_at = now() AT TIME ZONE 'utc'
+        WHERE key = OLD.blob_key;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE blobs SET ref_count = ref_count + 1, updated_at = now() AT TIME ZONE 'utc'
+        WHERE key = NEW.blob_key;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER attachments_blob_refs
+AFTER INSERT OR DELETE OR UPDATE OF blob_key ON attachments
+FOR EACH ROW EXECUTE FUNCTION attachments_blob_refs();
+
+-- Register the files uploaded before reference counting existed.
+INSERT INTO blobs (key, ref_count, created_at, updated_at)
+SELECT blob_key, count(*), now() AT TIME ZONE 'utc', now() AT TIME ZONE 'utc'
+FROM attachments
+GROUP BY blob_key;
+
+-- Avatars reference their thumb and large renditions, except those uploaded
+-- before renditions existed: they are bare file names such as "123.png"
+-- whose original is the only file stored.
+INSERT INTO blobs (key, ref_count, created_at, updated_at)
+SELECT regexp_replace(u.image_key, '(\.[^./]*)?$', '_' || v.name || '\1'), count(*), now() AT TIME ZONE 'utc', now() AT TIME ZONE 'utc'
+FROM users u
+CROSS JOIN (VALUES ('thumb'), ('large')) AS v (name)
+WHERE u.image_key LIKE '%/%'
+GROUP BY 1
+ON CONFLICT (key) DO UPDATE SET ref_count = blobs.ref_count + EXCLUDED.ref_count;
+
+INSERT INTO blobs (key, ref_count, created_at, updated_at)
+SELECT image_key, count(*), now() AT TIME ZONE 'utc', now() AT TIME ZONE 'utc'
+FROM users
+WHERE image_key <> '' AND image_key NOT LIKE '%/%'
+GROUP BY image_key
+ON CONFLICT (key) DO UPDATE SET ref_count = blobs.ref_count + EXCLUDED.ref_count;
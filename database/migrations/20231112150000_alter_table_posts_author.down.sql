@@ -0,0 +1,7 @@
+UPDATE users SET roles_id = (SELECT id FROM roles WHERE name = 'user')
+WHERE roles_id = (SELECT id FROM roles WHERE name = 'author');
+
+DELETE FROM roles WHERE name = 'author';
+
+DROP INDEX IF EXISTS idx_posts_user_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE posts ALTER COLUMN name TYPE VARCHAR(200);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id);
+
+INSERT INTO roles (name) VALUES ('author');
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+
+-- Existing posts are plain text, which renders to a single escaped paragraph.
+UPDATE posts SET content_html = '<p>' || replace(replace(replace(COALESCE(content, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;') || '</p>'
+WHERE content_html = '' AND COALESCE(content, '') <> '';
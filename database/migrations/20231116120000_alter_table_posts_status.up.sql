@@ -0,0 +1,8 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+-- Everything written before the workflow existed was already public.
+UPDATE posts SET status = 'published', published_at = COALESCE(created_at, now() AT TIME ZONE 'utc');
+
+CREATE INDEX IF NOT EXISTS posts_status_published_at_idx ON posts (status, published_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_idx ON post_revisions (post_id, id);
+
+-- Start every existing post's history from its current state.
+INSERT INTO post_revisions (post_id, name, content, user_id, created_at)
+SELECT id, name, COALESCE(content, ''), user_id, COALESCE(updated_at, created_at, now() AT TIME ZONE 'utc')
+FROM posts;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE posts DROP COLUMN IF EXISTS category_id;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS post_slugs;
+DROP INDEX IF EXISTS posts_slug_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,52 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR(100);
+
+-- Derive slugs for existing posts the way PostSlug does: numeric slugs and
+-- those naming another route under /posts get a prefix, and titles that
+-- collide get their id appended.
+UPDATE posts p SET slug = s.slug
+FROM (
+    SELECT id,
+        CASE WHEN row_number() OVER (PARTITION BY base ORDER BY id) = 1
+            THEN base ELSE base || '-' || id END AS slug
+    FROM (
+        SELECT id,
+            CASE WHEN raw IN ('new', 'preview', 'tags', 'categories', 'analytics') OR raw ~ '^[0-9]+$'
+                THEN 'post-' || raw ELSE raw END AS base
+        FROM (
+            SELECT id, COALESCE(NULLIF(trim(both '-' from lower(regexp_replace(name, '[^a-zA-Z0-9]+', '-', 'g'))), ''), 'post') AS raw
+            FROM posts
+        ) r
+    ) b
+) s
+WHERE p.id = s.id AND p.slug IS NULL;
+
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS posts_slug_idx ON posts (slug);
+
+-- Slugs a post used before it was renamed, so old links keep working.
+CREATE TABLE IF NOT EXISTS post_slugs (
+    slug VARCHAR(100) PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE
+);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_id_idx ON post_tags (tag_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS posts_search_vector_idx;
+DROP TRIGGER IF EXISTS posts_search_vector ON posts;
+DROP FUNCTION IF EXISTS posts_search_vector_update();
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
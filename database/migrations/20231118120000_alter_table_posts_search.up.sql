@@ -0,0 +1,22 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- Titles weigh more than the body when ranking.
+CREATE OR REPLACE FUNCTION posts_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_search_vector ON posts;
+CREATE TRIGGER posts_search_vector
+    BEFORE INSERT OR UPDATE OF name, content ON posts
+    FOR EACH ROW EXECUTE FUNCTION posts_search_vector_update();
+
+UPDATE posts SET search_vector =
+    setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(content, '')), 'B');
+
+CREATE INDEX IF NOT EXISTS posts_search_vector_idx ON posts USING GIN (search_vector);
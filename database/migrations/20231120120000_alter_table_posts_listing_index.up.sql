@@ -0,0 +1,2 @@
+-- Matches the keyset ordering of post listings.
+CREATE INDEX IF NOT EXISTS posts_listing_idx ON posts ((COALESCE(published_at, created_at)) DESC, id DESC);
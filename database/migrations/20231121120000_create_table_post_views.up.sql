@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS post_views (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS post_views_day_idx ON post_views (day);
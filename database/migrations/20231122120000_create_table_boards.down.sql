@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS cards_column_id_idx;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS type SMALLINT;
+UPDATE cards SET type = 1;
+ALTER TABLE cards DROP COLUMN IF EXISTS column_id;
+DROP TABLE IF EXISTS columns;
+DROP TABLE IF EXISTS boards;
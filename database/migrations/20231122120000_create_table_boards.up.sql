@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS boards (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(80) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
+
+CREATE TABLE IF NOT EXISTS columns (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    name VARCHAR(40) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
+
+CREATE INDEX IF NOT EXISTS columns_board_id_idx ON columns (board_id, position);
+
+-- Existing cards were one global list; they land in the first column of a
+-- default board.
+INSERT INTO boards (name) VALUES ('Workspace');
+
+INSERT INTO columns (board_id, name, position)
+SELECT b.id, c.name, c.position
+FROM boards b, (VALUES ('To do', 0), ('In progress', 1), ('Done', 2)) AS c(name, position);
+
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS column_id INT REFERENCES columns(id) ON DELETE CASCADE;
+
+UPDATE cards SET column_id = (SELECT id FROM columns WHERE position = 0 ORDER BY id LIMIT 1);
+
+ALTER TABLE cards ALTER COLUMN column_id SET NOT NULL;
+ALTER TABLE cards DROP COLUMN IF EXISTS type;
+
+CREATE INDEX IF NOT EXISTS cards_column_id_idx ON cards (column_id, position);
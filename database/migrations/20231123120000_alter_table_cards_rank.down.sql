@@ -0,0 +1,14 @@
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS position INT;
+
+UPDATE cards c SET position = ranked.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY column_id ORDER BY rank, id) - 1 AS position
+    FROM cards
+) ranked
+WHERE c.id = ranked.id;
+
+DROP INDEX IF EXISTS cards_column_id_rank_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS rank;
+CREATE INDEX IF NOT EXISTS cards_column_id_idx ON cards (column_id, position);
+
+ALTER TABLE boards DROP COLUMN IF EXISTS version;
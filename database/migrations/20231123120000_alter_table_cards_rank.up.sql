@@ -0,0 +1,19 @@
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 0;
+
+-- Ranks are lexorank keys compared byte by byte, hence the "C" collation.
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS rank TEXT COLLATE "C";
+
+-- Integer positions become fixed width keys. The trailing 'i' keeps them
+-- from ending in '0', which a lexorank key never does.
+UPDATE cards c SET rank = ranked.rank
+FROM (
+    SELECT id, lpad((row_number() OVER (PARTITION BY column_id ORDER BY position, id))::text, 6, '0') || 'i' AS rank
+    FROM cards
+) ranked
+WHERE c.id = ranked.id;
+
+ALTER TABLE cards ALTER COLUMN rank SET NOT NULL;
+
+DROP INDEX IF EXISTS cards_column_id_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS position;
+CREATE INDEX IF NOT EXISTS cards_column_id_rank_idx ON cards (column_id, rank);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS cards_parent_id_idx;
+
+ALTER TABLE cards DROP COLUMN IF EXISTS done;
@@ -0,0 +1,3 @@
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS done BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS cards_parent_id_idx ON cards (parent_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS card_labels;
+DROP TABLE IF EXISTS labels;
+
+DROP INDEX IF EXISTS cards_due_on_idx;
+DROP INDEX IF EXISTS cards_assignee_id_idx;
+
+ALTER TABLE cards DROP COLUMN IF EXISTS priority;
+ALTER TABLE cards DROP COLUMN IF EXISTS due_on;
+ALTER TABLE cards DROP COLUMN IF EXISTS assignee_id;
@@ -0,0 +1,24 @@
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS assignee_id INT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS due_on DATE;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'none'
+    CHECK (priority IN ('none', 'low', 'medium', 'high'));
+
+CREATE INDEX IF NOT EXISTS cards_assignee_id_idx ON cards (assignee_id);
+CREATE INDEX IF NOT EXISTS cards_due_on_idx ON cards (due_on) WHERE due_on IS NOT NULL AND NOT done;
+
+CREATE TABLE IF NOT EXISTS labels (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    name VARCHAR(30) NOT NULL,
+    color VARCHAR(7) NOT NULL CHECK (color ~ '^#[0-9a-f]{6}$'),
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    UNIQUE (board_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS card_labels (
+    card_id INT NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    label_id INT NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (card_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS card_labels_label_id_idx ON card_labels (label_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS card_events (
+    id SERIAL PRIMARY KEY,
+    card_id INT NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('created', 'moved', 'renamed', 'assigned', 'comment')),
+    from_value TEXT NOT NULL DEFAULT '',
+    to_value TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS card_events_card_id_idx ON card_events (card_id, created_at, id);
+
+INSERT INTO card_events (card_id, kind, created_at)
+SELECT id, 'created', created_at FROM cards;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('due_soon', 'mention', 'assigned')),
+    card_id INT REFERENCES cards(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    dedupe_key TEXT,
+    created_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP,
+    emailed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at DESC) WHERE in_app;
+CREATE INDEX IF NOT EXISTS notifications_pending_email_idx ON notifications (created_at) WHERE email AND emailed_at IS NULL;
+CREATE INDEX IF NOT EXISTS notifications_actor_id_idx ON notifications (actor_id, created_at) WHERE kind = 'mention';
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_dedupe_key_idx ON notifications (user_id, dedupe_key);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('due_soon', 'mention', 'assigned')),
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (user_id, kind)
+);
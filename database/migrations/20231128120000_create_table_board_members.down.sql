@@ -0,0 +1,8 @@
+DROP VIEW IF EXISTS board_access;
+DROP TABLE IF EXISTS board_members;
+ALTER TABLE boards DROP CONSTRAINT IF EXISTS boards_single_owner;
+ALTER TABLE boards DROP COLUMN IF EXISTS share_token;
+ALTER TABLE boards DROP COLUMN IF EXISTS team_id;
+ALTER TABLE boards DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
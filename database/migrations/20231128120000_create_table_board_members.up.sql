@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(80) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS team_members_user_id_idx ON team_members (user_id);
+
+-- A board belongs either to a user or to a team.
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS team_id INT REFERENCES teams(id) ON DELETE SET NULL;
+ALTER TABLE boards ADD COLUMN IF NOT EXISTS share_token VARCHAR(64) UNIQUE;
+ALTER TABLE boards ADD CONSTRAINT boards_single_owner CHECK (owner_id IS NULL OR team_id IS NULL);
+
+CREATE TABLE IF NOT EXISTS board_members (
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    PRIMARY KEY (board_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS board_members_user_id_idx ON board_members (user_id);
+
+-- board_access is the strongest role every user has on every board they can
+-- open: as its owner, through its team, or as one of its members.
+CREATE OR REPLACE VIEW board_access AS
+SELECT board_id, user_id, (ARRAY['viewer', 'editor', 'admin'])[MAX(rank)] AS role
+FROM (
+    SELECT id AS board_id, owner_id AS user_id, 3 AS rank FROM boards WHERE owner_id IS NOT NULL
+    UNION ALL
+    SELECT b.id, tm.user_id, array_position(ARRAY['viewer', 'editor', 'admin'], tm.role::text)
+    FROM boards b JOIN team_members tm ON tm.team_id = b.team_id
+    UNION ALL
+    SELECT board_id, user_id, array_position(ARRAY['viewer', 'editor', 'admin'], role::text) FROM board_members
+) grants
+GROUP BY board_id, user_id;
+
+-- Boards used to be open to everyone; they go to the first admin.
+UPDATE boards SET owner_id = (
+    SELECT u.id FROM users u JOIN roles r ON u.roles_id = r.id WHERE r.name = 'admin' ORDER BY u.id LIMIT 1
+) WHERE owner_id IS NULL AND team_id IS NULL;
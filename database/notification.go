@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_api/types"
+)
+
+const getNotificationQuery = `SELECT n.id, n.user_id, n.kind, n.card_id, n.message, n.url, n.in_app, n.email, n.created_at, n.read_at, u.email
+FROM notifications n JOIN users u ON n.user_id = u.id `
+
+// CreateNotification stores a notification on the channels the user chose
+// for its kind. Nothing is stored when the user turned that kind off, or
+// when one with the same dedupe key exists; notification.ID stays 0 then.
+func (s *DbConnection) CreateNotification(notification *types.Notification) error {
+	query := `INSERT INTO notifications
+	(user_id, kind, card_id, actor_id, message, url, in_app, email, dedupe_key, created_at)
+	SELECT u.id, $2, $3, $4, $5, $6, COALESCE(p.in_app, TRUE), COALESCE(p.email, FALSE), NULLIF($7, ''), $8
+	FROM users u LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.kind = $2
+	WHERE u.id = $1 AND (p.user_id IS NULL OR p.in_app OR p.email)
+	ON CONFLICT DO NOTHING
+	RETURNING id, in_app, email`
+
+	err := s.DB.QueryRow(
+		query,
+		notification.UserID,
+		notification.Kind,
+		notification.CardID,
+		notification.ActorID,
+		notification.Message,
+		notification.URL,
+		notification.DedupeKey,
+		notification.CreatedAt,
+	).Scan(&notification.ID, &notification.InApp, &notification.Email)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// CountMentionsSince counts the mention notifications the user caused after
+// since, for rate limiting.
+func (s *DbConnection) CountMentionsSince(actorID int, since time.Time) (int, error) {
+	var count int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE actor_id = $1 AND kind = 'mention' AND created_at > $2`,
+		actorID, since).Scan(&count)
+	return count, err
+}
+
+// GetNotifications returns the user's in-app notifications, newest first.
+func (s *DbConnection) GetNotifications(userID int, limit int) ([]*types.Notification, error) {
+	return s.queryNotifications(getNotificationQuery+`WHERE n.user_id = $1 AND n.in_app
+	ORDER BY n.created_at DESC, n.id DESC LIMIT $2`, userID, limit)
+}
+
+func (s *DbConnection) CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND in_app AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read and
+// returns it.
+func (s *DbConnection) MarkNotificationRead(id int, userID int) (*types.Notification, error) {
+	var notificationID int
+	err := s.DB.QueryRow(`UPDATE notifications SET read_at = COALESCE(read_at, $1) WHERE id = $2 AND user_id = $3 RETURNING id`,
+		time.Now().UTC(), id, userID).Scan(&notificationID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification %d not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	notifications, err := s.queryNotifications(getNotificationQuery+"WHERE n.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, fmt.Errorf("notification %d not found", id)
+	}
+	return notifications[0], nil
+}
+
+func (s *DbConnection) MarkAllNotificationsRead(userID int) error {
+	_, err := s.DB.Exec(`UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`, time.Now().UTC(), userID)
+	return err
+}
+
+// GetPendingEmailNotifications returns notifications still waiting to be
+// emailed, oldest first.
+func (s *DbConnection) GetPendingEmailNotifications(limit int) ([]*types.Notification, error) {
+	return s.queryNotifications(getNotificationQuery+`WHERE n.email AND n.emailed_at IS NULL
+	ORDER BY n.created_at, n.id LIMIT $1`, limit)
+}
+
+func (s *DbConnection) MarkNotificationEmailed(id int) error {
+	_, err := s.DB.Exec(`UPDATE notifications SET emailed_at = $1 WHERE id = $2`, time.Now().UTC(), id)
+	return err
+}
+
+// GetNotificationPreferences returns the user's preference for every kind,
+// falling back to in-app only for kinds they never changed.
+func (s *DbConnection) GetNotificationPreferences(userID int) ([]*types.NotificationPreference, error) {
+	rows, err := s.DB.Query(`SELECT kind, in_app, email FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[types.NotificationKind]*types.NotificationPreference{}
+	for rows.Next() {
+		pref := new(types.NotificationPreference)
+		if err := rows.Scan(&pref.Kind, &pref.InApp, &pref.Email); err != nil {
+			return nil, err
+		}
+		stored[pref.Kind] = pref
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := []*types.NotificationPreference{}
+	for _, kind := range types.NotificationKinds {
+		if pref, ok := stored[kind]; ok {
+			prefs = append(prefs, pref)
+		} else {
+			prefs = append(prefs, &types.NotificationPreference{Kind: kind, InApp: true})
+		}
+	}
+	return prefs, nil
+}
+
+func (s *DbConnection) UpdateNotificationPreferences(userID int, prefs []*types.NotificationPreference) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO notification_preferences (user_id, kind, in_app, email) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id, kind) DO UPDATE SET in_app = EXCLUDED.in_app, email = EXCLUDED.email`
+	for _, pref := range prefs {
+		if _, err := tx.Exec(query, userID, pref.Kind, pref.InApp, pref.Email); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCardsDueBetween returns the open, assigned cards due on a day between
+// from and to, both included, whose assignee can still open their board.
+func (s *DbConnection) GetCardsDueBetween(from time.Time, to time.Time) ([]*types.Card, error) {
+	rows, err := s.DB.Query(getCardQuery+`WHERE c.due_on BETWEEN $1::date AND $2::date
+	AND NOT c.done AND c.assignee_id IS NOT NULL
+	AND EXISTS (SELECT 1 FROM board_access a WHERE a.board_id = col.board_id AND a.user_id = c.assignee_id)
+	ORDER BY c.due_on, c.id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []*types.Card{}
+	for rows.Next() {
+		card, err := scanIntoCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+func (s *DbConnection) queryNotifications(query string, args ...any) ([]*types.Notification, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*types.Notification{}
+	for rows.Next() {
+		notification, err := scanIntoNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func scanIntoNotification(rows *sql.Rows) (*types.Notification, error) {
+	notification := new(types.Notification)
+	err := rows.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Kind,
+		&notification.CardID,
+		&notification.Message,
+		&notification.URL,
+		&notification.InApp,
+		&notification.Email,
+		&notification.CreatedAt,
+		&notification.ReadAt,
+		&notification.Recipient,
+	)
+	return notification, err
+}
@@ -5,21 +5,91 @@ import (
 	"fmt"
 	"time"
 
+	"go_api/pagination"
 	"go_api/types"
 
 	_ "github.com/lib/pq"
 )
 
-const getPostQuery = "SELECT p.id, p.name, p.content, image_url FROM posts p ORDER BY p.id"
+const getPostQuery = `SELECT p.id, p.name, p.slug, p.content, p.content_html, p.image_url, p.status, p.published_at, c.id, c.name, c.slug, p.user_id, COALESCE(u.first_name || ' ' || u.last_name, ''), p.created_at, p.updated_at
+FROM posts p LEFT JOIN users u ON p.user_id = u.id LEFT JOIN categories c ON p.category_id = c.id `
 
-func (s *DbConnection) GetPosts(page int) ([]*types.Post, error) {
-	offset := (page - 1) * 10
-	query := fmt.Sprintf("%s LIMIT %d OFFSET %d", getPostQuery, 10, offset)
+// publicPostCondition matches posts visitors may read. Scheduled posts count
+// as soon as their time has come, even before the scheduler flips them.
+const publicPostCondition = `(p.status = 'published' OR (p.status = 'scheduled' AND p.published_at <= now() AT TIME ZONE 'utc'))`
+
+// postSortKey orders listings newest first. Drafts have no publish date
+// yet and sort by when they were created.
+const postSortKey = `COALESCE(p.published_at, p.created_at)`
+
+// GetPosts lists the posts the viewer may see: published ones for everybody,
+// plus their own unpublished posts for authors and all of them for admins.
+// The filter optionally narrows the list to one tag or category. Pages are
+// keyset paginated on (published_at, id); the returned cursor is nil on the
+// last page.
+func (s *DbConnection) GetPosts(params pagination.Params, viewer *types.User, filter types.PostFilter) ([]*types.Post, *pagination.Cursor, error) {
+	var viewerID *int
+	isAdmin := false
+	if viewer != nil {
+		viewerID = &viewer.ID
+		isAdmin = viewer.Role.Name == types.RoleAdmin
+	}
+
+	var afterTime *time.Time
+	var afterID *int
+	if params.After != nil {
+		afterTime = &params.After.Time
+		afterID = &params.After.ID
+	}
+
+	query := fmt.Sprintf(`%sWHERE (%s OR p.user_id = $1 OR $2)
+	AND ($3 = '' OR c.slug = $3)
+	AND ($4 = '' OR EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON pt.tag_id = t.id WHERE pt.post_id = p.id AND t.slug = $4))
+	AND ($5::timestamp IS NULL OR (%s, p.id) < ($5, $6))
+	ORDER BY %s DESC, p.id DESC LIMIT %d`, getPostQuery, publicPostCondition, postSortKey, postSortKey, params.Size+1)
+
+	rows, err := s.DB.Query(query, viewerID, isAdmin, filter.Category, filter.Tag, afterTime, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	posts := []*types.Post{}
+	for rows.Next() {
+		post, err := scanIntoPost(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	posts, more := pagination.Trim(posts, params.Size)
+
+	if err := s.loadPostTags(posts...); err != nil {
+		return nil, nil, err
+	}
+
+	if !more {
+		return posts, nil, nil
+	}
+
+	last := posts[len(posts)-1]
+	return posts, &pagination.Cursor{Time: last.SortTime(), ID: last.ID}, nil
+}
+
+// GetPublishedPosts returns public posts, newest first, for feeds and the
+// sitemap. A limit of 0 returns all of them.
+func (s *DbConnection) GetPublishedPosts(limit int) ([]*types.Post, error) {
+	query := fmt.Sprintf("%sWHERE %s ORDER BY p.published_at DESC, p.id DESC", getPostQuery, publicPostCondition)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
 
 	rows, err := s.DB.Query(query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	posts := []*types.Post{}
 	for rows.Next() {
@@ -30,27 +100,56 @@ func (s *DbConnection) GetPosts(page int) ([]*types.Post, error) {
 		posts = append(posts, post)
 	}
 
+	if err := s.loadPostTags(posts...); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
 
+// CreatePost inserts the post together with its first revision.
 func (s *DbConnection) CreatePost(post *types.Post) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	post.Slug, err = uniquePostSlug(tx, types.PostSlug(post.Name), 0)
+	if err != nil {
+		return err
+	}
+
 	query := `insert into posts 
-	(name, content, image_url, created_at, updated_at)
-	values ($1, $2, $3, $4, $5)`
+	(name, slug, content, content_html, image_url, status, published_at, user_id, created_at, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 
-	_, err := s.DB.Query(
+	err = tx.QueryRow(
 		query,
 		post.Name,
+		post.Slug,
 		post.Content,
+		post.ContentHTML,
 		post.ImageURL,
+		post.Status,
+		post.PublishedAt,
+		post.UserID,
 		post.CreatedAt,
-		post.UpdatedAt)
-
+		post.UpdatedAt,
+	).Scan(&post.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if err := savePostTaxonomy(tx, post); err != nil {
+		return err
+	}
+
+	if err := createPostRevision(tx, types.NewPostRevision(post, post.UserID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *DbConnection) GetPost(id int) (*types.Post, error) {
@@ -58,24 +157,74 @@ func (s *DbConnection) GetPost(id int) (*types.Post, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		return scanIntoPost(rows)
+		post, err := scanIntoPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		rows.Close()
+		return post, s.loadPostTags(post)
 	}
 
 	return nil, fmt.Errorf("post %d not found", id)
 }
 
-func (s *DbConnection) UpdatePost(post *types.Post) error {
-	updateQuery := `update posts set name = $1 , content = $2, image_url = $3, updated_at= $5 where id = $6 RETURNING id`
+// GetPostBySlug finds the post by its current slug or, after a rename, by
+// one of its former slugs. Callers compare post.Slug to redirect old links.
+func (s *DbConnection) GetPostBySlug(slug string) (*types.Post, error) {
+	var id int
+	err := s.DB.QueryRow(`SELECT id FROM posts WHERE slug = $1
+	UNION ALL SELECT post_id FROM post_slugs WHERE slug = $1 LIMIT 1`, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("post %s not found", slug)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.GetPost(id)
+}
+
+// UpdatePost saves the post and records the saved state as a new revision
+// by editorID.
+func (s *DbConnection) UpdatePost(post *types.Post, editorID *int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var currentSlug string
+	err = tx.QueryRow(`SELECT slug FROM posts WHERE id = $1 FOR UPDATE`, post.ID).Scan(&currentSlug)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("post %d not found", post.ID)
+	} else if err != nil {
+		return err
+	}
+
+	post.Slug, err = uniquePostSlug(tx, types.PostSlug(post.Name), post.ID)
+	if err != nil {
+		return err
+	}
+	if post.Slug != currentSlug {
+		if err := movePostSlug(tx, post.ID, currentSlug, post.Slug); err != nil {
+			return err
+		}
+	}
+
+	updateQuery := `update posts set name = $1 , slug = $2, content = $3, content_html = $4, status = $5, published_at = $6, updated_at= $7 where id = $8 RETURNING id`
 
 	var postId int
-	err := s.DB.QueryRow(
+	err = tx.QueryRow(
 		updateQuery,
 		post.Name,
+		post.Slug,
 		post.Content,
-		post.ImageURL,
-		time.Now(),
+		post.ContentHTML,
+		post.Status,
+		post.PublishedAt,
+		time.Now().UTC(),
 		post.ID,
 	).Scan(&postId)
 
@@ -85,8 +234,27 @@ func (s *DbConnection) UpdatePost(post *types.Post) error {
 		return err
 	}
 
-	return nil
+	if err := savePostTaxonomy(tx, post); err != nil {
+		return err
+	}
 
+	if err := createPostRevision(tx, types.NewPostRevision(post, editorID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PublishScheduledPosts publishes every scheduled post whose time has come
+// and returns how many were published.
+func (s *DbConnection) PublishScheduledPosts(now time.Time) (int64, error) {
+	result, err := s.DB.Exec(`UPDATE posts SET status = 'published', updated_at = $1
+	WHERE status = 'scheduled' AND published_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
 
 func (s *DbConnection) DeletePost(id int) error {
@@ -106,11 +274,31 @@ func (s *DbConnection) DeletePost(id int) error {
 
 func scanIntoPost(rows *sql.Rows) (*types.Post, error) {
 	post := new(types.Post)
+	var categoryID sql.NullInt64
+	var categoryName, categorySlug sql.NullString
 	err := rows.Scan(
 		&post.ID,
 		&post.Name,
+		&post.Slug,
 		&post.Content,
+		&post.ContentHTML,
 		&post.ImageURL,
+		&post.Status,
+		&post.PublishedAt,
+		&categoryID,
+		&categoryName,
+		&categorySlug,
+		&post.UserID,
+		&post.Author,
+		&post.CreatedAt,
+		&post.UpdatedAt,
 	)
+	if categoryID.Valid {
+		post.Category = &types.Category{
+			ID:   int(categoryID.Int64),
+			Name: categoryName.String,
+			Slug: categorySlug.String,
+		}
+	}
 	return post, err
 }
@@ -32,6 +32,12 @@ func NewPostgresDbConnection() (*DbConnection, error) {
 
 	connString := "user=" + dbUser + " dbname=" + dbname + " password=" + dbPassword + " sslmode=disable"
 
+	return NewDbConnection(connString)
+}
+
+// NewDbConnection opens the Postgres database at connString and brings its
+// schema up to date.
+func NewDbConnection(connString string) (*DbConnection, error) {
 	db, err := sql.Open("postgres", connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %v", err)
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_api/types"
+)
+
+const getPostRevisionQuery = `SELECT r.id, r.post_id, r.name, r.content, r.user_id, COALESCE(u.first_name || ' ' || u.last_name, ''), r.created_at
+FROM post_revisions r LEFT JOIN users u ON r.user_id = u.id `
+
+// GetPostRevisions returns the post's revisions, newest first.
+func (s *DbConnection) GetPostRevisions(postID int) ([]*types.PostRevision, error) {
+	rows, err := s.DB.Query(getPostRevisionQuery+"WHERE r.post_id = $1 ORDER BY r.id DESC", postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*types.PostRevision{}
+	for rows.Next() {
+		revision, err := scanIntoPostRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *DbConnection) GetPostRevision(id int) (*types.PostRevision, error) {
+	rows, err := s.DB.Query(getPostRevisionQuery+"WHERE r.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		return scanIntoPostRevision(rows)
+	}
+
+	return nil, fmt.Errorf("revision %d not found", id)
+}
+
+func createPostRevision(tx *sql.Tx, revision *types.PostRevision) error {
+	query := `insert into post_revisions 
+	(post_id, name, content, user_id, created_at)
+	values ($1, $2, $3, $4, $5) RETURNING id`
+
+	return tx.QueryRow(
+		query,
+		revision.PostID,
+		revision.Name,
+		revision.Content,
+		revision.UserID,
+		revision.CreatedAt,
+	).Scan(&revision.ID)
+}
+
+func scanIntoPostRevision(rows *sql.Rows) (*types.PostRevision, error) {
+	revision := new(types.PostRevision)
+	err := rows.Scan(
+		&revision.ID,
+		&revision.PostID,
+		&revision.Name,
+		&revision.Content,
+		&revision.UserID,
+		&revision.Author,
+		&revision.CreatedAt,
+	)
+	return revision, err
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+
+	"go_api/pagination"
+	"go_api/types"
+)
+
+// searchHeadlineOptions marks matches with the private-use runes that
+// SearchResult.SnippetHTML turns into <mark> tags.
+const searchHeadlineOptions = "StartSel=" + types.SearchMatchStart + ", StopSel=" + types.SearchMatchStop +
+	", MaxWords=35, MinWords=15, MaxFragments=2"
+
+// SearchPosts runs a web-style full-text query over public posts, best
+// matches first, and reports whether more pages follow. Ranked results page
+// by offset rather than by cursor.
+func (s *DbConnection) SearchPosts(query string, params pagination.Params) ([]*types.SearchResult, bool, error) {
+	offset := (params.Page - 1) * params.Size
+	sqlQuery := fmt.Sprintf(`SELECT p.id, p.name, p.slug,
+		ts_headline('english', p.content, q, $2),
+		ts_rank(p.search_vector, q) AS rank
+	FROM posts p, websearch_to_tsquery('english', $1) q
+	WHERE p.search_vector @@ q AND %s
+	ORDER BY rank DESC, p.id DESC LIMIT %d OFFSET %d`, publicPostCondition, params.Size+1, offset)
+
+	rows, err := s.DB.Query(sqlQuery, query, searchHeadlineOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	results := []*types.SearchResult{}
+	for rows.Next() {
+		var slug string
+		result := &types.SearchResult{Kind: types.SearchPost}
+		if err := rows.Scan(&result.ID, &result.Title, &slug, &result.Snippet, &result.Rank); err != nil {
+			return nil, false, err
+		}
+		result.URL = "/posts/" + slug
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	results, more := pagination.Trim(results, params.Size)
+	return results, more, nil
+}
@@ -1,28 +1,108 @@
 package database
 
 import (
+	"time"
+
+	"go_api/pagination"
 	"go_api/types"
 )
 
 type Methods interface {
 	CreateUser(*types.User) error
 	GetUsers() ([]*types.User, error)
+	GetUsersPage(params pagination.Params) ([]*types.User, *pagination.Cursor, error)
 	GetUser(int) (*types.User, error)
 	UpdateUser(*types.User) error
 	DeleteUser(int) error
 	UpdateUserImage(*types.User) error
 	GetUserByEmail(string) (*types.User, error)
+	GetUsersByHandles(handles []string) ([]*types.User, error)
 
-	CreateCard(*types.Card) error
-	GetCards() ([]*types.Card, error)
+	CreateCard(card *types.Card, actorID *int) error
 	GetCard(int) (*types.Card, error)
-	UpdateCard(*types.Card) error
-	DeleteCard(int) error
-	ReorderCards([]string) ([]*types.Card, error)
+	UpdateCard(card *types.Card, actorID *int) error
+	GetCardTree(int) (*types.Card, error)
+	ToggleCardDone(int) error
+	DeleteCard(id int, cascade bool) error
+	GetBoards(userID int) ([]*types.Board, error)
+	GetBoard(int) (*types.Board, error)
+	CreateBoard(*types.Board) error
+	CreateColumn(*types.Column) error
+	ImportBoard(board *types.Board, labels []*types.Label, actorID *int) error
+	MoveCard(boardID int, move *types.MoveCardRequest, actorID *int) error
+	GetCardEvents(cardID int) ([]*types.CardEvent, error)
+	CreateCardComment(*types.CardEvent) error
+	GetLabels(boardID int) ([]*types.Label, error)
+	CreateLabel(*types.Label) error
+	GetCardsDueBetween(from time.Time, to time.Time) ([]*types.Card, error)
+
+	GetBoardRole(boardID int, userID int) (types.MemberRole, error)
+	GetCardBoardRole(cardID int, userID int) (int, types.MemberRole, error)
+	GetBoardMembers(boardID int) ([]*types.Member, error)
+	GetBoardUsers(boardID int) ([]*types.User, error)
+	SetBoardMember(boardID int, userID int, role types.MemberRole) error
+	RemoveBoardMember(boardID int, userID int) error
+	SetBoardShareToken(boardID int, token string) error
+	GetSharedBoard(token string) (*types.Board, error)
+	GetTeams(userID int) ([]*types.Team, error)
+	CreateTeam(team *types.Team, creatorID int) error
+	GetTeamRole(teamID int, userID int) (types.MemberRole, error)
+	SetTeamMember(teamID int, userID int, role types.MemberRole) error
+	RemoveTeamMember(teamID int, userID int) error
+
+	CreateNotification(*types.Notification) error
+	CountMentionsSince(actorID int, since time.Time) (int, error)
+	GetNotifications(userID int, limit int) ([]*types.Notification, error)
+	CountUnreadNotifications(userID int) (int, error)
+	MarkNotificationRead(id int, userID int) (*types.Notification, error)
+	MarkAllNotificationsRead(userID int) error
+	GetPendingEmailNotifications(limit int) ([]*types.Notification, error)
+	MarkNotificationEmailed(id int) error
+	GetNotificationPreferences(userID int) ([]*types.NotificationPreference, error)
+	UpdateNotificationPreferences(userID int, prefs []*types.NotificationPreference) error
 
 	CreatePost(*types.Post) error
-	GetPosts(page int) ([]*types.Post, error)
+	GetPosts(params pagination.Params, viewer *types.User, filter types.PostFilter) ([]*types.Post, *pagination.Cursor, error)
 	GetPost(int) (*types.Post, error)
-	UpdatePost(*types.Post) error
+	GetPostBySlug(string) (*types.Post, error)
+	GetPublishedPosts(limit int) ([]*types.Post, error)
+	UpdatePost(post *types.Post, editorID *int) error
 	DeletePost(int) error
+	PublishScheduledPosts(time.Time) (int64, error)
+	GetPostRevisions(postID int) ([]*types.PostRevision, error)
+	GetPostRevision(id int) (*types.PostRevision, error)
+	GetTags() ([]*types.Tag, error)
+	GetTag(slug string) (*types.Tag, error)
+	GetCategories() ([]*types.Category, error)
+	GetPublicCategories() ([]*types.Category, error)
+	GetCategory(slug string) (*types.Category, error)
+	SearchPosts(query string, params pagination.Params) ([]*types.SearchResult, bool, error)
+
+	AddPostViews([]*types.PostViews) error
+	GetTopPosts(since time.Time, limit int) ([]*types.PostViewStats, error)
+	GetDailyViews(since time.Time) ([]*types.DailyViews, error)
+
+	CreateComment(*types.Comment) error
+	GetComment(int) (*types.Comment, error)
+	GetPostComments(postID int, viewerID *int) ([]*types.Comment, error)
+	GetPendingComments() ([]*types.Comment, error)
+	UpdateCommentStatus(int, types.CommentStatus) error
+	DeleteComment(int) error
+	HasApprovedComments(userID int) (bool, error)
+	CountCommentsSince(userID int, since time.Time) (int, error)
+
+	CreateAttachment(*types.Attachment) error
+	GetAttachments(types.AttachmentKind, int) ([]*types.Attachment, error)
+	GetAttachment(int) (*types.Attachment, error)
+	DeleteAttachment(int) error
+
+	CreateUpload(*types.Upload) error
+	GetUpload(string) (*types.Upload, error)
+	UpdateUploadOffset(string, int64) error
+	DeleteUpload(string) error
+	GetStaleUploads(time.Time) ([]*types.Upload, error)
+
+	CreateBlob(blob *types.Blob, put func() error) error
+	GetLiveBlobKeys(time.Time) (map[string]bool, error)
+	DeleteUnreferencedBlob(key string, cutoff time.Time, remove func() error) (bool, error)
 }
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_api/types"
+
+	"github.com/lib/pq"
+)
+
+// GetTags returns the tags used by at least one public post.
+func (s *DbConnection) GetTags() ([]*types.Tag, error) {
+	rows, err := s.DB.Query(`SELECT t.id, t.name, t.slug FROM tags t
+	WHERE EXISTS (SELECT 1 FROM post_tags pt JOIN posts p ON pt.post_id = p.id WHERE pt.tag_id = t.id AND ` + publicPostCondition + `)
+	ORDER BY t.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*types.Tag{}
+	for rows.Next() {
+		tag := new(types.Tag)
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Slug); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (s *DbConnection) GetTag(slug string) (*types.Tag, error) {
+	tag := new(types.Tag)
+	err := s.DB.QueryRow(`SELECT id, name, slug FROM tags WHERE slug = $1`, slug).Scan(&tag.ID, &tag.Name, &tag.Slug)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tag %s not found", slug)
+	}
+	return tag, err
+}
+
+// GetCategories returns every category, for authors to pick from.
+func (s *DbConnection) GetCategories() ([]*types.Category, error) {
+	return s.queryCategories(`SELECT c.id, c.name, c.slug FROM categories c ORDER BY c.name`)
+}
+
+// GetPublicCategories returns the categories of at least one public post.
+func (s *DbConnection) GetPublicCategories() ([]*types.Category, error) {
+	return s.queryCategories(`SELECT c.id, c.name, c.slug FROM categories c
+	WHERE EXISTS (SELECT 1 FROM posts p WHERE p.category_id = c.id AND ` + publicPostCondition + `)
+	ORDER BY c.name`)
+}
+
+func (s *DbConnection) queryCategories(query string) ([]*types.Category, error) {
+	rows, err := s.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*types.Category{}
+	for rows.Next() {
+		category := new(types.Category)
+		if err := rows.Scan(&category.ID, &category.Name, &category.Slug); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (s *DbConnection) GetCategory(slug string) (*types.Category, error) {
+	category := new(types.Category)
+	err := s.DB.QueryRow(`SELECT id, name, slug FROM categories WHERE slug = $1`, slug).Scan(&category.ID, &category.Name, &category.Slug)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("category %s not found", slug)
+	}
+	return category, err
+}
+
+// loadPostTags fills in the tags of the given posts with a single query.
+func (s *DbConnection) loadPostTags(posts ...*types.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(posts))
+	byID := make(map[int]*types.Post, len(posts))
+	for i, post := range posts {
+		ids[i] = int64(post.ID)
+		byID[post.ID] = post
+		post.Tags = []*types.Tag{}
+	}
+
+	rows, err := s.DB.Query(`SELECT pt.post_id, t.id, t.name, t.slug FROM post_tags pt
+	JOIN tags t ON pt.tag_id = t.id WHERE pt.post_id = ANY($1) ORDER BY t.name`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		tag := new(types.Tag)
+		if err := rows.Scan(&postID, &tag.ID, &tag.Name, &tag.Slug); err != nil {
+			return err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Tags = append(post.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// savePostTaxonomy stores the post's category and replaces its tags,
+// creating categories and tags that don't exist yet.
+func savePostTaxonomy(tx *sql.Tx, post *types.Post) error {
+	var categoryID *int
+	if post.Category != nil && post.Category.Slug != "" {
+		err := tx.QueryRow(`INSERT INTO categories (name, slug) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug RETURNING id, name`,
+			post.Category.Name, post.Category.Slug).Scan(&post.Category.ID, &post.Category.Name)
+		if err != nil {
+			return err
+		}
+		categoryID = &post.Category.ID
+	}
+
+	if _, err := tx.Exec(`UPDATE posts SET category_id = $1 WHERE id = $2`, categoryID, post.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_id = $1`, post.ID); err != nil {
+		return err
+	}
+
+	for _, tag := range post.Tags {
+		err := tx.QueryRow(`INSERT INTO tags (name, slug) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug RETURNING id, name`,
+			tag.Name, tag.Slug).Scan(&tag.ID, &tag.Name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO post_tags (post_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, post.ID, tag.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uniquePostSlug returns base, or base with a numeric suffix, such that no
+// other post uses it now or used it before. postID is the post being saved,
+// whose own current and former slugs are free to take.
+func uniquePostSlug(tx *sql.Tx, base string, postID int) (string, error) {
+	slug := base
+	for n := 2; ; n++ {
+		var taken bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM posts WHERE slug = $1 AND id <> $2)
+		OR EXISTS (SELECT 1 FROM post_slugs WHERE slug = $1 AND post_id <> $2)`, slug, postID).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// movePostSlug keeps the old slug as a redirect and takes the new one out of
+// the redirects in case the post is going back to a former slug.
+func movePostSlug(tx *sql.Tx, postID int, from, to string) error {
+	if _, err := tx.Exec(`INSERT INTO post_slugs (slug, post_id) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING`, from, postID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`DELETE FROM post_slugs WHERE slug = $1 AND post_id = $2`, to, postID)
+	return err
+}
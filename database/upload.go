@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_api/types"
+
+	_ "github.com/lib/pq"
+)
+
+const getUploadQuery = "SELECT u.id, u.card_id, u.file_name, u.size, u.upload_offset, u.checksum, u.created_at, u.updated_at FROM uploads u "
+
+func (s *DbConnection) CreateUpload(upload *types.Upload) error {
+	query := `insert into uploads 
+	(id, card_id, file_name, size, upload_offset, checksum, created_at, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := s.DB.Exec(
+		query,
+		upload.ID,
+		upload.CardID,
+		upload.FileName,
+		upload.Size,
+		upload.Offset,
+		upload.Checksum,
+		upload.CreatedAt,
+		upload.UpdatedAt)
+
+	return err
+}
+
+func (s *DbConnection) GetUpload(id string) (*types.Upload, error) {
+	rows, err := s.DB.Query(getUploadQuery+"WHERE u.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		return scanIntoUpload(rows)
+	}
+
+	return nil, fmt.Errorf("upload %s not found", id)
+}
+
+func (s *DbConnection) UpdateUploadOffset(id string, offset int64) error {
+	updateQuery := `update uploads set upload_offset = $1, updated_at = $2 where id = $3 RETURNING id`
+
+	var uploadID string
+	err := s.DB.QueryRow(updateQuery, offset, time.Now().UTC(), id).Scan(&uploadID)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("upload %s not found", id)
+	}
+	return err
+}
+
+func (s *DbConnection) DeleteUpload(id string) error {
+	_, err := s.DB.Exec(`DELETE FROM uploads WHERE id = $1`, id)
+	return err
+}
+
+// GetStaleUploads returns unfinished uploads that have not received a chunk
+// since before.
+func (s *DbConnection) GetStaleUploads(before time.Time) ([]*types.Upload, error) {
+	rows, err := s.DB.Query(getUploadQuery+"WHERE u.updated_at < $1", before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := []*types.Upload{}
+	for rows.Next() {
+		upload, err := scanIntoUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+func scanIntoUpload(rows *sql.Rows) (*types.Upload, error) {
+	upload := new(types.Upload)
+	err := rows.Scan(
+		&upload.ID,
+		&upload.CardID,
+		&upload.FileName,
+		&upload.Size,
+		&upload.Offset,
+		&upload.Checksum,
+		&upload.CreatedAt,
+		&upload.UpdatedAt,
+	)
+	return upload, err
+}
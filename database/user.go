@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"time"
 
+	"go_api/images"
+	"go_api/pagination"
 	"go_api/types"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-const getUserQuery = "SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.created_at, u.updated_at,image_url, r.id as role_id, r.name as role_name FROM users u JOIN roles r ON u.roles_id = r.id "
+const getUserQuery = "SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.created_at, u.updated_at, u.image_key, r.id as role_id, r.name as role_name FROM users u JOIN roles r ON u.roles_id = r.id "
 
 func (s *DbConnection) GetUsers() ([]*types.User, error) {
 	rows, err := s.DB.Query(getUserQuery)
@@ -30,9 +32,49 @@ func (s *DbConnection) GetUsers() ([]*types.User, error) {
 	return users, nil
 }
 
+// GetUsersPage returns one page of users in the order they registered,
+// and the cursor of the next page, nil on the last one.
+func (s *DbConnection) GetUsersPage(params pagination.Params) ([]*types.User, *pagination.Cursor, error) {
+	var afterTime *time.Time
+	var afterID *int
+	if params.After != nil {
+		afterTime = &params.After.Time
+		afterID = &params.After.ID
+	}
+
+	query := fmt.Sprintf(`%sWHERE ($1::timestamp IS NULL OR (u.created_at, u.id) > ($1, $2))
+	ORDER BY u.created_at, u.id LIMIT %d`, getUserQuery, params.Size+1)
+
+	rows, err := s.DB.Query(query, afterTime, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := []*types.User{}
+	for rows.Next() {
+		user, err := scanIntoUser(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	users, more := pagination.Trim(users, params.Size)
+	if !more {
+		return users, nil, nil
+	}
+
+	last := users[len(users)-1]
+	return users, &pagination.Cursor{Time: last.CreatedAt, ID: last.ID}, nil
+}
+
 func (s *DbConnection) CreateUser(user *types.User) error {
 	query := `insert into users 
-	(first_name, last_name, email, password, created_at, updated_at, roles_id, image_url)
+	(first_name, last_name, email, password, created_at, updated_at, roles_id, image_key)
 	values ($1, $2, $3, $4, $5, $6, 2, '')`
 
 	_, err := s.DB.Query(
@@ -77,6 +119,30 @@ func (s *DbConnection) GetUserByEmail(email string) (*types.User, error) {
 	return nil, fmt.Errorf("user %s not found", email)
 }
 
+// GetUsersByHandles returns the users mentioned as one of handles. The
+// handle is built as types.User.Handle builds it, so callers should still
+// compare the two.
+func (s *DbConnection) GetUsersByHandles(handles []string) ([]*types.User, error) {
+	rows, err := s.DB.Query(getUserQuery+`WHERE concat_ws('.',
+	NULLIF(lower(regexp_replace(u.first_name, '[^[:alnum:]]', '', 'g')), ''),
+	NULLIF(lower(regexp_replace(u.last_name, '[^[:alnum:]]', '', 'g')), '')) = ANY($1)`, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*types.User{}
+	for rows.Next() {
+		user, err := scanIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 func (s *DbConnection) UpdateUser(user *types.User) error {
 	updateQuery := `update users set first_name = $1 , last_name = $2, updated_at= $3 where id = $4 RETURNING id`
 
@@ -100,10 +166,16 @@ func (s *DbConnection) UpdateUser(user *types.User) error {
 }
 
 func (s *DbConnection) DeleteUser(id int) error {
-	deleteQuery := `DELETE FROM users WHERE id = $1 RETURNING id`
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `DELETE FROM users WHERE id = $1 RETURNING image_key`
 
-	var userID int
-	err := s.DB.QueryRow(deleteQuery, id).Scan(&userID)
+	user := &types.User{ID: id}
+	err = tx.QueryRow(deleteQuery, id).Scan(&user.ImageKey)
 
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("user with id %d not found", id)
@@ -111,28 +183,43 @@ func (s *DbConnection) DeleteUser(id int) error {
 		return err
 	}
 
-	return nil
+	if err := adjustBlobRefs(tx, images.VariantKeys(user.ImageKey), -1); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// UpdateUserImage points the user at a new avatar and moves the blob
+// references from the old renditions to the new ones in one transaction.
 func (s *DbConnection) UpdateUserImage(user *types.User) error {
-	updateQuery := `update users set image_url = $1 , updated_at= $2 where id = $3 RETURNING id`
-
-	var userId int
-	err := s.DB.QueryRow(
-		updateQuery,
-		user.ImageURL,
-		time.Now(),
-		user.ID,
-	).Scan(&userId)
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	previous := &types.User{ID: user.ID}
+	err = tx.QueryRow(`SELECT image_key FROM users WHERE id = $1 FOR UPDATE`, user.ID).Scan(&previous.ImageKey)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("user %d not found", user.ID)
 	} else if err != nil {
 		return err
 	}
 
-	return nil
+	updateQuery := `update users set image_key = $1 , updated_at= $2 where id = $3`
+	if _, err := tx.Exec(updateQuery, user.ImageKey, time.Now(), user.ID); err != nil {
+		return err
+	}
+
+	if err := adjustBlobRefs(tx, images.VariantKeys(previous.ImageKey), -1); err != nil {
+		return err
+	}
+	if err := adjustBlobRefs(tx, images.VariantKeys(user.ImageKey), 1); err != nil {
+		return err
+	}
 
+	return tx.Commit()
 }
 
 func scanIntoUser(rows *sql.Rows) (*types.User, error) {
@@ -146,7 +233,7 @@ func scanIntoUser(rows *sql.Rows) (*types.User, error) {
 		&user.Password,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-		&user.ImageURL,
+		&user.ImageKey,
 		&role.ID,
 		&role.Name,
 	)
@@ -0,0 +1,144 @@
+// Package diff computes line-level differences between two texts.
+package diff
+
+import "strings"
+
+// maxTableCells caps the size of the LCS table, which takes one int per
+// pair of changed old and new lines. Larger changed blocks are shown as
+// replaced whole instead.
+const maxTableCells = 4 << 20
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+func (o Op) String() string {
+	switch o {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Line is one line of a diff. OldNumber and NewNumber are 1-based line
+// numbers in each text, or 0 when the line does not exist on that side.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// Lines returns the edit script that turns a into b, based on the longest
+// common subsequence of their lines. Deletions come before insertions
+// within a changed block. A changed block too large to compare line by line
+// is deleted and inserted whole.
+func Lines(a, b string) []Line {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	// Common prefix and suffix are cheap to match and keep the table small
+	// for the usual case of a few edited lines.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(oldLines)+len(newLines))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: Equal, Text: oldLines[i], OldNumber: i + 1, NewNumber: i + 1})
+	}
+
+	lines = appendMiddle(lines, oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix], prefix, prefix)
+
+	for i := suffix; i > 0; i-- {
+		oldIndex := len(oldLines) - i
+		newIndex := len(newLines) - i
+		lines = append(lines, Line{Op: Equal, Text: oldLines[oldIndex], OldNumber: oldIndex + 1, NewNumber: newIndex + 1})
+	}
+
+	return lines
+}
+
+// Changed reports whether the diff contains any insertion or deletion.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func appendMiddle(lines []Line, oldLines, newLines []string, oldOffset, newOffset int) []Line {
+	n, m := len(oldLines), len(newLines)
+	if n > 0 && m > 0 && n > maxTableCells/m {
+		return appendReplace(lines, oldLines, newLines, oldOffset, newOffset)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// oldLines[i:] and newLines[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && oldLines[i] == newLines[j]:
+			lines = append(lines, Line{Op: Equal, Text: oldLines[i], OldNumber: oldOffset + i + 1, NewNumber: newOffset + j + 1})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, Line{Op: Delete, Text: oldLines[i], OldNumber: oldOffset + i + 1})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: newLines[j], NewNumber: newOffset + j + 1})
+			j++
+		}
+	}
+
+	return lines
+}
+
+func appendReplace(lines []Line, oldLines, newLines []string, oldOffset, newOffset int) []Line {
+	for i, text := range oldLines {
+		lines = append(lines, Line{Op: Delete, Text: text, OldNumber: oldOffset + i + 1})
+	}
+	for j, text := range newLines {
+		lines = append(lines, Line{Op: Insert, Text: text, NewNumber: newOffset + j + 1})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,195 @@
+// Package feed renders Atom and RSS feeds and XML sitemaps.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"time"
+)
+
+// Feed is the format-independent description of a syndication feed.
+type Feed struct {
+	Title    string
+	Subtitle string
+	// URL is the page the feed describes and FeedURL the feed itself.
+	URL     string
+	FeedURL string
+	Updated time.Time
+	Entries []*Entry
+}
+
+type Entry struct {
+	// ID must stay the same for the lifetime of the entry, even when its
+	// URL changes.
+	ID         string
+	Title      string
+	URL        string
+	Author     string
+	Summary    string
+	Content    string // HTML
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	ID       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders the feed as Atom 1.0.
+func (f *Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Subtitle,
+		ID:       f.FeedURL,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.URL, Rel: "alternate", Type: "text/html"},
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, entry := range f.Entries {
+		e := &atomEntry{
+			Title:     entry.Title,
+			ID:        entry.ID,
+			Link:      atomLink{Href: entry.URL, Rel: "alternate", Type: "text/html"},
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Updated:   entry.Updated.UTC().Format(time.RFC3339),
+		}
+		if entry.Author != "" {
+			e.Author = &atomPerson{Name: entry.Author}
+		}
+		for _, category := range entry.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: category})
+		}
+		if entry.Summary != "" {
+			e.Summary = &atomText{Type: "text", Body: entry.Summary}
+		}
+		if entry.Content != "" {
+			e.Content = &atomText{Type: "html", Body: entry.Content}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+
+	return marshal(feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	AtomLink      atomLink   `xml:"atom:link"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders the feed as RSS 2.0. RSS has no updated date per item, so
+// readers only see pubDate.
+func (f *Feed) RSS() ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.URL,
+			Description:   f.Subtitle,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			AtomLink:      atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	for _, entry := range f.Entries {
+		description := entry.Content
+		if description == "" {
+			description = entry.Summary
+		}
+		feed.Channel.Items = append(feed.Channel.Items, &rssItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			GUID:        rssGUID{Value: entry.ID},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+			Author:      entry.Author,
+			Categories:  entry.Categories,
+			Description: description,
+		})
+	}
+
+	return marshal(feed)
+}
+
+// ETag returns a strong entity tag for a rendered document.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func marshal(v any) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,37 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// SitemapURL is one page listed in a sitemap. LastModified is left out
+// when zero.
+type SitemapURL struct {
+	Loc          string
+	LastModified time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap renders the pages as a sitemaps.org XML sitemap.
+func Sitemap(urls []SitemapURL) ([]byte, error) {
+	set := urlSet{URLs: make([]sitemapURL, 0, len(urls))}
+	for _, u := range urls {
+		entry := sitemapURL{Loc: u.Loc}
+		if !u.LastModified.IsZero() {
+			entry.LastMod = u.LastModified.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	return marshal(set)
+}
@@ -8,10 +8,24 @@ require (
 	github.com/golang-jwt/jwt/v5 v5.0.0
 	github.com/golang-migrate/migrate/v4 v4.16.2
 	github.com/lib/pq v1.10.9
-	golang.org/x/crypto v0.13.0
+	golang.org/x/crypto v0.14.0
 )
 
-require github.com/gorilla/websocket v1.5.0 // indirect
+require (
+	github.com/alecthomas/chroma/v2 v2.12.0
+	github.com/microcosm-cc/bluemonday v1.0.26
+	github.com/yuin/goldmark v1.5.6
+	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
+)
+
+require github.com/gorilla/websocket v1.5.0
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+)
 
 require (
 	github.com/hashicorp/errwrap v1.1.0 // indirect
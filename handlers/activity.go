@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+// ActivityResponse feeds a card's activity stream. OOB marks the list for
+// an out-of-band swap when it rides along with another response.
+type ActivityResponse struct {
+	CardID     int
+	Events     []*types.CardEvent
+	CanComment bool
+	OOB        bool
+}
+
+// handleCreateCardComment adds a comment to a card's activity and answers
+// with just that entry, which the form appends to the list.
+func (s *ApiRouter) handleCreateCardComment(w http.ResponseWriter, r *http.Request) {
+	createCommentReq := new(types.CreateCardCommentRequest)
+	if err := json.NewDecoder(r.Body).Decode(createCommentReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, err := s.currentUser(r)
+	if err != nil || user == nil {
+		permissionDenied(w)
+		return
+	}
+
+	card, err := s.store.GetCard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	comment, err := types.NewCardComment(id, user.ID, createCommentReq.Body)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.CreateCardComment(comment); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	comment.Author = user.FirstName + " " + user.LastName
+
+	// Only people who can open the board hear about its cards.
+	if members, err := s.store.GetBoardUsers(card.BoardID); err == nil {
+		s.notifyMentions(members, &user.ID, comment.Author, comment.Body, fmt.Sprintf("on %q", card.Name), fmt.Sprintf("/workspace/%d", id), &id)
+	} else {
+		log.Println("Error loading board members for mentions:", err)
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "card/cardEvent.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, comment)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) cardActivity(r *http.Request, cardID int) (ActivityResponse, error) {
+	events, err := s.store.GetCardEvents(cardID)
+	if err != nil {
+		return ActivityResponse{}, err
+	}
+
+	return ActivityResponse{
+		CardID:     cardID,
+		Events:     events,
+		CanComment: memberRole(r).Allows(types.MemberEditor),
+	}, nil
+}
+
+// actorID is the logged-in user recorded against changes to cards, or nil
+// when there is none.
+func (s *ApiRouter) actorID(r *http.Request) *int {
+	user, err := s.currentUser(r)
+	if err != nil || user == nil {
+		return nil
+	}
+	return &user.ID
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"go_api/analytics"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+const (
+	viewFlushInterval = time.Minute
+	analyticsDays     = 30
+	topPostsLimit     = 10
+	sparklineWidth    = 600
+	sparklineHeight   = 80
+)
+
+type AnalyticsResponse struct {
+	Days        int
+	TotalViews  int
+	TopPosts    []*types.PostViewStats
+	Daily       []*types.DailyViews
+	Sparkline   string
+	ChartWidth  int
+	ChartHeight int
+}
+
+func (s *ApiRouter) handleGetAnalytics(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -(analyticsDays - 1))
+
+	topPosts, err := s.store.GetTopPosts(since, topPostsLimit)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	daily, err := s.store.GetDailyViews(since)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := AnalyticsResponse{
+		Days:        analyticsDays,
+		TopPosts:    topPosts,
+		Daily:       daily,
+		ChartWidth:  sparklineWidth,
+		ChartHeight: sparklineHeight,
+	}
+
+	values := make([]int, len(daily))
+	for i, day := range daily {
+		values[i] = day.Views
+		data.TotalViews += day.Views
+	}
+	data.Sparkline = analytics.Sparkline(values, sparklineWidth, sparklineHeight)
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "analytics/dashboard.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) withAnalyticsViewer(next http.Handler) http.Handler {
+	return s.withPermission((*types.User).CanViewAnalytics)(next)
+}
+
+// recordView counts a visit to a public post. Only a salted hash of the
+// visitor's address and user agent is kept, and only for the day.
+func (s *ApiRouter) recordView(r *http.Request, post *types.Post) {
+	s.views.Record(post.ID, analytics.ClientIP(r, s.trustedProxies), r.UserAgent())
+}
+
+// flushViews periodically writes the buffered view counts to the database.
+// Counts that fail to save are kept for the next attempt.
+func (s *ApiRouter) flushViews() {
+	ticker := time.NewTicker(viewFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		views := s.views.Drain()
+		if len(views) == 0 {
+			continue
+		}
+
+		if err := s.store.AddPostViews(views); err != nil {
+			log.Println("Error saving post views:", err)
+			s.views.Requeue(views)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+
+	"go_api/storage"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+type AttachmentsResponse struct {
+	UploadURL   string
+	Attachments []*types.Attachment
+	Editable    bool
+}
+
+func (s *ApiRouter) handleUploadPostAttachment(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+	s.uploadAttachment(w, r, types.AttachmentPost, post.ID)
+}
+
+func (s *ApiRouter) handleUploadCardAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.uploadAttachment(w, r, types.AttachmentCard, id)
+}
+
+func (s *ApiRouter) handleGetCardAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderAttachments(w, r, types.AttachmentCard, id)
+}
+
+func (s *ApiRouter) handleDeletePostAttachment(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+	s.deleteAttachment(w, r, types.AttachmentPost, post.ID)
+}
+
+func (s *ApiRouter) handleDeleteCardAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.deleteAttachment(w, r, types.AttachmentCard, id)
+}
+
+// uploadAttachment stores the files in the request and attaches them to the
+// post or card with the given id, which the caller has already resolved and
+// checked access to.
+func (s *ApiRouter) uploadAttachment(w http.ResponseWriter, r *http.Request, kind types.AttachmentKind, id int) {
+	limits := types.AttachmentRules[kind]
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(limits.MaxSize); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	for _, fileHeader := range r.MultipartForm.File["file"] {
+		if fileHeader.Size > limits.MaxSize {
+			s.handleError(w, r, fmt.Errorf("%s is larger than %d MB", fileHeader.Filename, limits.MaxSize>>20))
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		contentType, err := sniffContentType(file)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if !limits.Allows(contentType) {
+			s.handleError(w, r, fmt.Errorf("unsupported file type %s", contentType))
+			return
+		}
+
+		digest, err := hashFile(file)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		key := storage.ContentKey("attachments", digest, "")
+		if err := s.putBlob(key, digest, file, fileHeader.Size, contentType); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		fileName := filepath.Base(fileHeader.Filename)
+		attachment := types.NewAttachment(kind, id, key, fileName, contentType, fileHeader.Size)
+		if err := s.store.CreateAttachment(attachment); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	s.renderAttachments(w, r, kind, id)
+}
+
+func (s *ApiRouter) deleteAttachment(w http.ResponseWriter, r *http.Request, kind types.AttachmentKind, id int) {
+	attachmentID, err := getIntParam(r, "attachmentID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	attachment, err := s.store.GetAttachment(attachmentID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	ownerID := attachment.CardID
+	if kind == types.AttachmentPost {
+		ownerID = attachment.PostID
+	}
+	if ownerID == nil || *ownerID != id {
+		s.handleError(w, r, fmt.Errorf("attachment %d does not belong to %s %d", attachmentID, kind, id))
+		return
+	}
+
+	// The blob may be shared with other attachments; it is left for the
+	// garbage collector once its last reference is gone.
+	if err := s.store.DeleteAttachment(attachmentID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderAttachments(w, r, kind, id)
+}
+
+func (s *ApiRouter) getAttachments(kind types.AttachmentKind, ownerID int) (AttachmentsResponse, error) {
+	attachments, err := s.store.GetAttachments(kind, ownerID)
+	if err != nil {
+		return AttachmentsResponse{}, err
+	}
+
+	for _, attachment := range attachments {
+		attachment.URL = s.blobs.URL(attachment.Key)
+	}
+
+	uploadURL := fmt.Sprintf("/workspace/%d/attachments", ownerID)
+	if kind == types.AttachmentPost {
+		uploadURL = fmt.Sprintf("/posts/%d/attachments", ownerID)
+	}
+
+	// Anyone who reaches a mutating attachment route was already allowed to
+	// edit the owner; the post details page narrows this for visitors.
+	return AttachmentsResponse{
+		UploadURL:   uploadURL,
+		Attachments: attachments,
+		Editable:    true,
+	}, nil
+}
+
+func (s *ApiRouter) renderAttachments(w http.ResponseWriter, r *http.Request, kind types.AttachmentKind, ownerID int) {
+	data, err := s.getAttachments(kind, ownerID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	// Viewers of a board may list a card's attachments but not change them.
+	if kind == types.AttachmentCard {
+		data.Editable = memberRole(r).Allows(types.MemberEditor)
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "attachment/attachments.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -297,6 +298,57 @@ func (s *ApiRouter) withRoleAuth(d database.Methods, requiredRole string) func(h
 	}
 }
 
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// currentUser returns the logged in user of the request, preferring the one
+// a middleware already loaded into the context.
+func (s *ApiRouter) currentUser(r *http.Request) (*types.User, error) {
+	if user, ok := r.Context().Value(userContextKey).(*types.User); ok {
+		return user, nil
+	}
+
+	tokenString, err := extractTokenFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := validateJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*types.LoginResponse)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return s.store.GetUserByEmail(claims.Email)
+}
+
+// withPostAuthor only lets through users allowed to write blog posts and
+// stores them in the request context.
+func (s *ApiRouter) withPostAuthor(next http.Handler) http.Handler {
+	return s.withPermission((*types.User).CanWritePosts)(next)
+}
+
+// withPermission lets a request through only for a logged in user the check
+// accepts, and stores that user in the request context.
+func (s *ApiRouter) withPermission(allowed func(*types.User) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := s.currentUser(r)
+			if err != nil || !allowed(user) {
+				permissionDenied(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func createJWT(user *user.User) (string, error) {
 
 	expirationTime := time.Now().Add(3600 * time.Second)
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"go_api/storage"
+	"go_api/types"
+)
+
+const (
+	blobGracePeriod     = 24 * time.Hour
+	blobCollectInterval = time.Hour
+)
+
+// blobPrefixes are the parts of the blob store managed through the blobs
+// table. Anything else, such as the favicon, is never collected.
+var blobPrefixes = []string{"avatars/", "attachments/"}
+
+// putBlob stores data under its content addressed key. When an identical
+// blob is already stored the upload is skipped and the existing one reused.
+func (s *ApiRouter) putBlob(key, digest string, r io.Reader, size int64, contentType string) error {
+	return s.store.CreateBlob(types.NewBlob(key, digest, size, contentType), func() error {
+		return s.blobs.Put(key, r, contentType)
+	})
+}
+
+// hashFile returns the hex encoded SHA-256 of a seekable upload and rewinds
+// it afterwards.
+func hashFile(f io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// collectGarbage periodically deletes stored files that no row references
+// and that have been left alone for longer than the grace period. Files
+// orphaned by a failed request are cleaned up here instead of inline.
+func (s *ApiRouter) collectGarbage() {
+	ticker := time.NewTicker(blobCollectInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.collectBlobs(time.Now().UTC().Add(-blobGracePeriod)); err != nil {
+			log.Println("Error collecting blobs:", err)
+		}
+	}
+}
+
+func (s *ApiRouter) collectBlobs(cutoff time.Time) error {
+	live, err := s.store.GetLiveBlobKeys(cutoff)
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range blobPrefixes {
+		stored, err := s.blobs.List(prefix)
+		if err != nil {
+			return err
+		}
+
+		for _, blob := range stored {
+			// Keys containing "/." are temporary files of uploads in flight.
+			if live[blob.Key] || blob.ModTime.After(cutoff) || strings.Contains(blob.Key, "/.") {
+				continue
+			}
+
+			deleted, err := s.store.DeleteUnreferencedBlob(blob.Key, cutoff, func() error {
+				if err := s.blobs.Delete(blob.Key); err != nil && err != storage.ErrNotFound {
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if deleted {
+				log.Println("Collected unreferenced blob", blob.Key)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go_api/boardfile"
+
+	templates "go_api/templates"
+)
+
+const maxBoardImportSize = 10 << 20
+
+// Formats accepted by the board import form.
+const (
+	importFormatJSON   = "json"
+	importFormatTrello = "trello"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ImportResponse previews an import: what would be created, or why the file
+// cannot be imported.
+type ImportResponse struct {
+	File  *boardfile.File
+	Error string
+}
+
+func (s *ApiRouter) handleExportBoardJSON(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.exportBoard(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := file.JSON()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	writeDownload(w, "application/json; charset=utf-8", exportFilename(file.Name, "json"), body)
+}
+
+func (s *ApiRouter) handleExportBoardCSV(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.exportBoard(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := file.CSV()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	writeDownload(w, "text/csv; charset=utf-8", exportFilename(file.Name, "csv"), body)
+}
+
+func (s *ApiRouter) exportBoard(w http.ResponseWriter, r *http.Request) (*boardfile.File, bool) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return nil, false
+	}
+
+	board, err := s.store.GetBoard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return nil, false
+	}
+
+	labels, err := s.store.GetLabels(id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return nil, false
+	}
+
+	return boardfile.FromBoard(board, labels), true
+}
+
+// handleImportBoard creates a new board, owned by the user, from an uploaded
+// file. With dryRun set nothing is stored and the board that would be
+// created is listed instead.
+func (s *ApiRouter) handleImportBoard(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBoardImportSize)
+	if err := r.ParseMultipartForm(maxBoardImportSize); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		s.renderImport(w, r, ImportResponse{Error: "Choose a file to import."})
+		return
+	}
+
+	upload, err := files[0].Open()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	defer upload.Close()
+
+	file, err := parseBoardFile(r.FormValue("format"), upload)
+	if err != nil {
+		s.renderImport(w, r, ImportResponse{Error: err.Error()})
+		return
+	}
+
+	board, labels, err := file.Board()
+	if err != nil {
+		s.renderImport(w, r, ImportResponse{File: file, Error: err.Error()})
+		return
+	}
+
+	if r.FormValue("dryRun") == "true" {
+		s.renderImport(w, r, ImportResponse{File: file})
+		return
+	}
+
+	if err := s.setBoardOwner(r, board, ""); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.ImportBoard(board, labels, s.actorID(r)); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/workspace/boards/%d", board.ID))
+}
+
+func parseBoardFile(format string, r io.Reader) (*boardfile.File, error) {
+	switch format {
+	case importFormatJSON:
+		return boardfile.ParseJSON(r)
+	case importFormatTrello:
+		return boardfile.ParseTrello(r)
+	default:
+		return nil, errors.New("Choose the format of the file.")
+	}
+}
+
+func (s *ApiRouter) renderImport(w http.ResponseWriter, r *http.Request, data ImportResponse) {
+	tmpl, err := template.ParseFS(templates.Templates, "workspace/boardImport.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func writeDownload(w http.ResponseWriter, contentType string, filename string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(body)
+}
+
+// exportFilename turns a board name into a file name safe for any system.
+func exportFilename(name string, extension string) string {
+	base := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if base == "" {
+		base = "board"
+	}
+	return base + "." + extension
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 
+	"go_api/chat"
+
 	templates "go_api/templates"
 )
 
@@ -47,6 +49,17 @@ func (s *ApiRouter) handleChat(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// handleChatWs joins the chat. The nickname shown with messages is whatever
+// the sender picked, so mentions are only raised for logged in users, as
+// themselves.
+func (s *ApiRouter) handleChatWs(w http.ResponseWriter, r *http.Request) {
+	member := chat.Member{}
+	if user, err := s.currentUser(r); err == nil && user != nil {
+		member = chat.Member{ID: user.ID, Name: user.FirstName + " " + user.LastName}
+	}
+	chat.ServeWs(s.chat, w, r, member)
+}
+
 func (s *ApiRouter) handleChatLogin(w http.ResponseWriter, r *http.Request) {
 	var req ChatNickname
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
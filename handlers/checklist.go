@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+// handleCreateChecklistItem adds a sub-card at the end of a card's checklist.
+func (s *ApiRouter) handleCreateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	createCardReq := new(types.CreateCardRequest)
+	if err := json.NewDecoder(r.Body).Decode(createCardReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	parent, err := s.store.GetCard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	item, err := types.NewCard(parent.ColumnID, createCardReq.Name, "", &parent.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.CreateCard(item, s.actorID(r)); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderChecklist(w, r, parent.ID)
+	s.publishBoard(parent.BoardID)
+}
+
+// handleToggleChecklistItem flips an item between done and open. The posted
+// root is the card whose checklist is on screen; the item has to be part of
+// it, and that checklist is rendered again so its progress stays right.
+func (s *ApiRouter) handleToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	rootID := id
+	if root := r.PostForm.Get("root"); root != "" {
+		if rootID, err = strconv.Atoi(root); err != nil {
+			s.handleError(w, r, fmt.Errorf("invalid root given %s", root))
+			return
+		}
+	}
+
+	root, err := s.store.GetCardTree(rootID)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+	if !root.Contains(id) {
+		s.handleError(w, r, fmt.Errorf("card %d is not in the checklist of card %d", id, rootID))
+		return
+	}
+
+	if err := s.store.ToggleCardDone(id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderChecklist(w, r, rootID)
+	s.publishBoard(root.BoardID)
+}
+
+func (s *ApiRouter) renderChecklist(w http.ResponseWriter, r *http.Request, cardID int) {
+	card, err := s.store.GetCardTree(cardID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "card/cardChecklist.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, card)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_api/mail"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+const (
+	// Users may post commentRateLimit comments per commentRateWindow.
+	commentRateLimit  = 5
+	commentRateWindow = 10 * time.Minute
+)
+
+type CommentsResponse struct {
+	PostID      int
+	Comments    []*types.Comment
+	Count       int
+	CanComment  bool
+	CanModerate bool
+	Error       string
+	Notice      string
+}
+
+type ModerationResponse struct {
+	Comments []*types.Comment
+}
+
+func (s *ApiRouter) handleGetComments(w http.ResponseWriter, r *http.Request) {
+	post, user, ok := s.getCommentablePost(w, r)
+	if !ok {
+		return
+	}
+
+	s.renderComments(w, r, post, user, CommentsResponse{})
+}
+
+// handleCreateComment adds a comment or a reply. Comments from users who
+// never had one approved wait in the moderation queue.
+func (s *ApiRouter) handleCreateComment(w http.ResponseWriter, r *http.Request) {
+	createCommentReq := new(types.CreateCommentRequest)
+	if err := json.NewDecoder(r.Body).Decode(createCommentReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	post, user, ok := s.getCommentablePost(w, r)
+	if !ok {
+		return
+	}
+	if user == nil {
+		permissionDenied(w)
+		return
+	}
+
+	recent, err := s.store.CountCommentsSince(user.ID, time.Now().UTC().Add(-commentRateWindow))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if recent >= commentRateLimit {
+		s.renderComments(w, r, post, user, CommentsResponse{Error: "You are commenting too fast. Please try again in a few minutes."})
+		return
+	}
+
+	parentID, err := s.commentParent(post, createCommentReq.ParentID)
+	if err != nil {
+		s.renderComments(w, r, post, user, CommentsResponse{Error: err.Error()})
+		return
+	}
+
+	status := types.CommentApproved
+	if !user.CanModerateComments() && !user.CanEditPost(post) {
+		trusted, err := s.store.HasApprovedComments(user.ID)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if !trusted {
+			status = types.CommentPending
+		}
+	}
+
+	comment, err := types.NewComment(post.ID, parentID, user.ID, createCommentReq.Body, status)
+	if err != nil {
+		s.renderComments(w, r, post, user, CommentsResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.store.CreateComment(comment); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	notice := ""
+	if comment.Status == types.CommentPending {
+		notice = "Thanks! Your comment will appear once a moderator has approved it."
+	} else {
+		comment.Author = user.FirstName + " " + user.LastName
+		s.notifyPostAuthor(post, comment)
+		s.notifyCommentMentions(post, comment)
+	}
+
+	s.renderComments(w, r, post, user, CommentsResponse{Notice: notice})
+}
+
+func (s *ApiRouter) handleGetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	comments, err := s.store.GetPendingComments()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "comment/moderation.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, ModerationResponse{Comments: comments})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) handleApproveComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := s.getComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.UpdateCommentStatus(comment.ID, types.CommentApproved); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if comment.Status != types.CommentApproved {
+		if post, err := s.store.GetPost(comment.PostID); err == nil {
+			s.notifyPostAuthor(post, comment)
+			s.notifyCommentMentions(post, comment)
+		}
+	}
+}
+
+func (s *ApiRouter) handleRejectComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := s.getComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.UpdateCommentStatus(comment.ID, types.CommentRejected); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := s.getComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteComment(comment.ID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) withCommentModerator(next http.Handler) http.Handler {
+	return s.withPermission((*types.User).CanModerateComments)(next)
+}
+
+// getCommentablePost loads the post named in the URL if the viewer may see
+// it. The user is nil for anonymous visitors.
+func (s *ApiRouter) getCommentablePost(w http.ResponseWriter, r *http.Request) (*types.Post, *types.User, bool) {
+	post, err := s.findPost(r)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return nil, nil, false
+	}
+
+	user, err := s.currentUser(r)
+	if err != nil {
+		user = nil
+	}
+
+	if !post.IsPublic(time.Now().UTC()) && (user == nil || !user.CanEditPost(post)) {
+		s.handleNotFound(w, r)
+		return nil, nil, false
+	}
+
+	return post, user, true
+}
+
+// commentParent resolves the comment being replied to. Replies to a reply
+// attach to its top-level comment, keeping threads one level deep.
+func (s *ApiRouter) commentParent(post *types.Post, parentIDStr string) (*int, error) {
+	if parentIDStr == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(parentIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent comment given %s", parentIDStr)
+	}
+
+	parent, err := s.store.GetComment(id)
+	if err != nil || parent.PostID != post.ID || parent.Status != types.CommentApproved {
+		return nil, errors.New("The comment you replied to no longer exists.")
+	}
+
+	if parent.ParentID != nil {
+		return parent.ParentID, nil
+	}
+	return &parent.ID, nil
+}
+
+func (s *ApiRouter) getComment(w http.ResponseWriter, r *http.Request) (*types.Comment, bool) {
+	id, err := getIntParam(r, "commentID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return nil, false
+	}
+
+	comment, err := s.store.GetComment(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return nil, false
+	}
+
+	return comment, true
+}
+
+func (s *ApiRouter) renderComments(w http.ResponseWriter, r *http.Request, post *types.Post, user *types.User, data CommentsResponse) {
+	var viewerID *int
+	if user != nil {
+		viewerID = &user.ID
+		data.CanComment = true
+		data.CanModerate = user.CanModerateComments()
+	}
+
+	comments, err := s.store.GetPostComments(post.ID, viewerID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data.PostID = post.ID
+	data.Count = len(comments)
+	data.Comments = types.ThreadComments(comments)
+
+	tmpl, err := template.ParseFS(templates.Templates, "comment/comments.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// notifyPostAuthor emails the post's author about a new visible comment,
+// unless they wrote it themselves. Mail goes out in the background so a
+// slow mail server doesn't hold up the response. The link to the comment
+// is left out unless SITE_URL is set.
+func (s *ApiRouter) notifyPostAuthor(post *types.Post, comment *types.Comment) {
+	if post.UserID == nil || *post.UserID == comment.UserID {
+		return
+	}
+
+	go func() {
+		author, err := s.store.GetUser(*post.UserID)
+		if err != nil {
+			log.Println("Error loading post author for notification:", err)
+			return
+		}
+
+		link := ""
+		if base := configuredSiteURL(); base != "" {
+			link = fmt.Sprintf("\n\n%s%s#comment-%d", base, postURL(post), comment.ID)
+		} else {
+			log.Println("SITE_URL is not set, leaving the link out of the comment notification")
+		}
+
+		msg := mail.Message{
+			To:      []string{author.Email},
+			Subject: fmt.Sprintf("New comment on %q", post.Name),
+			Body:    fmt.Sprintf("%s commented on %q:\n\n%s%s\n", comment.Author, post.Name, comment.Body, link),
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			log.Println("Error sending comment notification:", err)
+		}
+	}()
+}
+
+// notifyCommentMentions tells the users mentioned in an approved comment.
+// Comments waiting for moderation only do so once they are approved.
+func (s *ApiRouter) notifyCommentMentions(post *types.Post, comment *types.Comment) {
+	s.notifyMentions(nil, &comment.UserID, comment.Author, comment.Body, fmt.Sprintf("on %q", post.Name),
+		fmt.Sprintf("%s#comment-%d", postURL(post), comment.ID), nil)
+}
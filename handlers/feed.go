@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go_api/analytics"
+	"go_api/feed"
+	"go_api/markdown"
+	"go_api/types"
+)
+
+const (
+	feedSize = 20
+	// defaultRobotsDisallow keeps crawlers out of pages behind a login and
+	// of boards shared by link.
+	defaultRobotsDisallow = "/auth/,/users/,/workspace/,/notifications/,/shared/"
+)
+
+func (s *ApiRouter) handleAtomFeed(w http.ResponseWriter, r *http.Request) {
+	postFeed, err := s.postFeed(r, "/posts/feed.atom")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	body, err := postFeed.Atom()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	writeCacheable(w, r, "application/atom+xml; charset=utf-8", body, postFeed.Updated)
+}
+
+func (s *ApiRouter) handleRSSFeed(w http.ResponseWriter, r *http.Request) {
+	postFeed, err := s.postFeed(r, "/posts/feed.rss")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	body, err := postFeed.RSS()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	writeCacheable(w, r, "application/rss+xml; charset=utf-8", body, postFeed.Updated)
+}
+
+// handleSitemap lists the public pages: the static ones, every published
+// post and the tag and category archives.
+func (s *ApiRouter) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	base := s.siteURL(r)
+
+	posts, err := s.store.GetPublishedPosts(0)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	var lastModified time.Time
+	urls := []feed.SitemapURL{{Loc: base + "/"}, {Loc: base + "/posts"}}
+	for _, post := range posts {
+		updated := postUpdated(post)
+		if updated.After(lastModified) {
+			lastModified = updated
+		}
+		urls = append(urls, feed.SitemapURL{Loc: base + postURL(post), LastModified: updated})
+	}
+	urls[1].LastModified = lastModified
+
+	tags, err := s.store.GetTags()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	for _, tag := range tags {
+		urls = append(urls, feed.SitemapURL{Loc: base + "/posts/tags/" + tag.Slug})
+	}
+
+	categories, err := s.store.GetPublicCategories()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	for _, category := range categories {
+		urls = append(urls, feed.SitemapURL{Loc: base + "/posts/categories/" + category.Slug})
+	}
+
+	body, err := feed.Sitemap(urls)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	writeCacheable(w, r, "application/xml; charset=utf-8", body, lastModified)
+}
+
+// handleRobots builds robots.txt from ROBOTS_DISALLOW, a comma separated
+// list of path prefixes, and points crawlers at the sitemap.
+func (s *ApiRouter) handleRobots(w http.ResponseWriter, r *http.Request) {
+	disallow, ok := os.LookupEnv("ROBOTS_DISALLOW")
+	if !ok {
+		disallow = defaultRobotsDisallow
+	}
+
+	rules := []string{}
+	for _, path := range strings.Split(disallow, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			rules = append(rules, "Disallow: "+path)
+		}
+	}
+	if len(rules) == 0 {
+		// An empty Disallow allows everything.
+		rules = append(rules, "Disallow:")
+	}
+
+	var robotsTxt bytes.Buffer
+	robotsTxt.WriteString("User-agent: *\n")
+	robotsTxt.WriteString(strings.Join(rules, "\n"))
+	fmt.Fprintf(&robotsTxt, "\n\nSitemap: %s/sitemap.xml\n", s.siteURL(r))
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(robotsTxt.Bytes())
+}
+
+func (s *ApiRouter) postFeed(r *http.Request, path string) (*feed.Feed, error) {
+	base := s.siteURL(r)
+
+	posts, err := s.store.GetPublishedPosts(feedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	postFeed := &feed.Feed{
+		Title:    "Blog",
+		Subtitle: "Latest posts",
+		URL:      base + "/posts",
+		FeedURL:  base + path,
+	}
+
+	for _, post := range posts {
+		updated := postUpdated(post)
+		if updated.After(postFeed.Updated) {
+			postFeed.Updated = updated
+		}
+
+		entry := &feed.Entry{
+			// Ids never change, unlike slugs, so they make stable entry ids.
+			ID:        fmt.Sprintf("%s/posts/%d", base, post.ID),
+			Title:     post.Name,
+			URL:       base + postURL(post),
+			Author:    post.Author,
+			Summary:   markdown.Excerpt(post.ContentHTML, postExcerptLength),
+			Content:   post.ContentHTML,
+			Published: *post.PublishedAt,
+			Updated:   updated,
+		}
+		if post.Category != nil {
+			entry.Categories = append(entry.Categories, post.Category.Name)
+		}
+		for _, tag := range post.Tags {
+			entry.Categories = append(entry.Categories, tag.Name)
+		}
+		postFeed.Entries = append(postFeed.Entries, entry)
+	}
+
+	return postFeed, nil
+}
+
+// postUpdated is when a public post last changed, which is never earlier
+// than when it went public.
+func postUpdated(post *types.Post) time.Time {
+	if post.PublishedAt != nil && post.PublishedAt.After(post.UpdatedAt) {
+		return *post.PublishedAt
+	}
+	return post.UpdatedAt
+}
+
+// writeCacheable writes a generated document with an ETag, answering
+// conditional requests for an unchanged document with 304.
+func writeCacheable(w http.ResponseWriter, r *http.Request, contentType string, body []byte, lastModified time.Time) {
+	etag := feed.ETag(body)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// siteURL is the public origin used in absolute links on the pages served
+// to r. SITE_URL wins; otherwise it is taken from the request, honouring the
+// scheme set by a trusted proxy.
+func (s *ApiRouter) siteURL(r *http.Request) string {
+	if configured := configuredSiteURL(); configured != "" {
+		return configured
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if analytics.FromTrustedProxy(r, s.trustedProxies) {
+		if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded == "http" || forwarded == "https" {
+			scheme = forwarded
+		}
+	}
+	return scheme + "://" + r.Host
+}
+
+// configuredSiteURL is SITE_URL without a trailing slash, or "" when it is
+// not set. Links that leave the site, such as those in emails, only use it:
+// the request's Host is whatever the client sent.
+func configuredSiteURL() string {
+	return strings.TrimRight(os.Getenv("SITE_URL"), "/")
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"go_api/chat"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+func boardRoom(boardID int) string {
+	return fmt.Sprintf("board:%d", boardID)
+}
+
+// handleBoardLive subscribes a board viewer's websocket to the board's
+// updates and adds them to its presence list.
+func (s *ApiRouter) handleBoardLive(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+	s.resolveUserImages(user)
+	member := chat.Member{
+		ID:       user.ID,
+		Name:     user.FirstName + " " + user.LastName,
+		ImageURL: user.ImageURLs["thumb"],
+		Role:     string(memberRole(r)),
+	}
+
+	chat.ServeRoom(s.boards, w, r, boardRoom(id), member)
+}
+
+// renderBoardPresence is sent to a board's viewers whenever one of them
+// joins or leaves.
+func renderBoardPresence(room string, members []chat.Member) []byte {
+	message, err := renderFragment(members, "workspace/boardPresence.html")
+	if err != nil {
+		log.Printf("rendering presence for %s: %v", room, err)
+	}
+	return message
+}
+
+// publishBoard pushes the stored layout of a board to everyone viewing it,
+// after a change that can move cards between or within columns.
+func (s *ApiRouter) publishBoard(boardID int) {
+	board, err := s.store.GetBoard(boardID)
+	if err != nil {
+		log.Printf("publishing board %d: %v", boardID, err)
+		return
+	}
+
+	messages := map[string][]byte{}
+	for _, role := range types.MemberRoles {
+		message, err := renderFragment(BoardResponse{Board: board, Role: role}, "workspace/boardColumns.html", "workspace/cardDraggable.html", "workspace/card.html")
+		if err != nil {
+			log.Printf("publishing board %d: %v", boardID, err)
+			return
+		}
+		messages[string(role)] = message
+	}
+	s.boards.PublishByRole(boardRoom(boardID), messages)
+}
+
+// publishCard pushes a single changed card to everyone viewing its board.
+// Sub-cards are not on the board, so there is nothing to replace for them.
+func (s *ApiRouter) publishCard(card *types.Card) {
+	if card.Parent != nil {
+		return
+	}
+
+	message, err := renderFragment(card, "workspace/card.html")
+	if err != nil {
+		log.Printf("publishing card %d: %v", card.ID, err)
+		return
+	}
+	s.boards.Publish(boardRoom(card.BoardID), message)
+}
+
+func renderFragment(data any, files ...string) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates.Templates, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"go_api/database"
+	"go_api/types"
+
+	templates "go_api/templates"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const roleContextKey contextKey = "memberRole"
+
+// MembersResponse feeds the page where a board's admins manage who can open
+// it and its public link.
+type MembersResponse struct {
+	Board    *types.Board
+	Members  []*types.Member
+	Roles    []types.MemberRole
+	ShareURL string
+	Error    string
+	Notice   string
+}
+
+type TeamsResponse struct {
+	Teams []*types.Team
+	Roles []types.MemberRole
+	// UserID is the viewer, who cannot remove themselves from the list.
+	UserID int
+	Error  string
+}
+
+// withBoardRole lets a request through only for a user with at least role
+// on the board in the URL. Boards the user cannot open are not found, so
+// their ids give nothing away.
+func (s *ApiRouter) withBoardRole(role types.MemberRole) func(http.Handler) http.Handler {
+	return s.withMemberRole(role, func(r *http.Request, userID int) (types.MemberRole, error) {
+		id, err := getID(r)
+		if err != nil {
+			return "", err
+		}
+		return s.store.GetBoardRole(id, userID)
+	})
+}
+
+// withCardRole is withBoardRole for the board of the card in the URL.
+func (s *ApiRouter) withCardRole(role types.MemberRole) func(http.Handler) http.Handler {
+	return s.withMemberRole(role, func(r *http.Request, userID int) (types.MemberRole, error) {
+		id, err := getID(r)
+		if err != nil {
+			return "", err
+		}
+		_, cardRole, err := s.store.GetCardBoardRole(id, userID)
+		return cardRole, err
+	})
+}
+
+// withTeamRole is withBoardRole for the team in the URL.
+func (s *ApiRouter) withTeamRole(role types.MemberRole) func(http.Handler) http.Handler {
+	return s.withMemberRole(role, func(r *http.Request, userID int) (types.MemberRole, error) {
+		teamID, err := getIntParam(r, "teamID")
+		if err != nil {
+			return "", err
+		}
+		return s.store.GetTeamRole(teamID, userID)
+	})
+}
+
+// withMemberRole stores the user and the role lookup found for them in the
+// request context, once that role allows required.
+func (s *ApiRouter) withMemberRole(required types.MemberRole, lookup func(r *http.Request, userID int) (types.MemberRole, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := s.currentUser(r)
+			if err != nil || user == nil {
+				permissionDenied(w)
+				return
+			}
+
+			role, err := lookup(r, user.ID)
+			if err != nil || role == "" {
+				s.handleNotFound(w, r)
+				return
+			}
+			if !role.Allows(required) {
+				permissionDenied(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			ctx = context.WithValue(ctx, roleContextKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// memberRole is the role a board, card or team middleware found for the
+// user of the request.
+func memberRole(r *http.Request) types.MemberRole {
+	role, _ := r.Context().Value(roleContextKey).(types.MemberRole)
+	return role
+}
+
+func (s *ApiRouter) handleGetBoardMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	response, err := s.boardMembers(id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "workspace/boardMembers.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, response)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// handleAddBoardMember gives a registered user a role on the board, or
+// changes the one they were given before.
+func (s *ApiRouter) handleAddBoardMember(w http.ResponseWriter, r *http.Request) {
+	addMemberReq := new(types.AddMemberRequest)
+	if err := json.NewDecoder(r.Body).Decode(addMemberReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	notice := ""
+	member, err := s.findMember(addMemberReq)
+	if err == nil {
+		err = s.store.SetBoardMember(id, member.UserID, member.Role)
+		notice = member.Email + " is now a " + string(member.Role) + " of this board."
+	}
+	if err != nil {
+		s.renderBoardMembers(w, r, id, err.Error(), "")
+		return
+	}
+
+	s.renderBoardMembers(w, r, id, "", notice)
+}
+
+func (s *ApiRouter) handleRemoveBoardMember(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	userID, err := getIntParam(r, "userID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.RemoveBoardMember(id, userID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardMembers(w, r, id, "", "")
+}
+
+// handleShareBoard creates the board's public link. Sharing a board again
+// replaces its link, so the old one stops working.
+func (s *ApiRouter) handleShareBoard(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	token, err := types.NewShareToken()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.SetBoardShareToken(id, token); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardMembers(w, r, id, "", "Anyone with this link can view the board.")
+}
+
+func (s *ApiRouter) handleUnshareBoard(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.SetBoardShareToken(id, ""); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardMembers(w, r, id, "", "The public link no longer works.")
+}
+
+// boardMembers loads the members page of a board. Its public link is built
+// from SITE_URL only, never from the request's Host, so that the token is
+// not handed to another origin.
+func (s *ApiRouter) boardMembers(boardID int) (MembersResponse, error) {
+	board, err := s.store.GetBoard(boardID)
+	if err != nil {
+		return MembersResponse{}, err
+	}
+
+	members, err := s.store.GetBoardMembers(boardID)
+	if err != nil {
+		return MembersResponse{}, err
+	}
+
+	response := MembersResponse{Board: board, Members: members, Roles: types.MemberRoles}
+	if board.ShareToken != "" {
+		response.ShareURL = configuredSiteURL() + "/shared/boards/" + board.ShareToken
+	}
+	return response, nil
+}
+
+func (s *ApiRouter) renderBoardMembers(w http.ResponseWriter, r *http.Request, boardID int, errMsg string, notice string) {
+	response, err := s.boardMembers(boardID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	response.Error = errMsg
+	response.Notice = notice
+
+	tmpl, err := template.ParseFS(templates.Templates, "workspace/boardMembers.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.ExecuteTemplate(w, "boardMembers", response)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// findMember validates an add member request and finds the user it names.
+func (s *ApiRouter) findMember(req *types.AddMemberRequest) (*types.Member, error) {
+	member, err := types.NewMember(req)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.GetUserByEmail(member.Email)
+	if err != nil {
+		return nil, errors.New("No user is registered with that email.")
+	}
+	member.UserID = user.ID
+	return member, nil
+}
+
+// handleCreateTeam creates a team with the user as its admin.
+func (s *ApiRouter) handleCreateTeam(w http.ResponseWriter, r *http.Request) {
+	createTeamReq := new(types.CreateTeamRequest)
+	if err := json.NewDecoder(r.Body).Decode(createTeamReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+
+	team, err := types.NewTeam(createTeamReq.Name)
+	if err == nil {
+		err = s.store.CreateTeam(team, user.ID)
+	}
+	if err != nil {
+		s.renderTeams(w, r, user.ID, err.Error())
+		return
+	}
+
+	s.renderTeams(w, r, user.ID, "")
+}
+
+func (s *ApiRouter) handleAddTeamMember(w http.ResponseWriter, r *http.Request) {
+	addMemberReq := new(types.AddMemberRequest)
+	if err := json.NewDecoder(r.Body).Decode(addMemberReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	teamID, err := getIntParam(r, "teamID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+
+	member, err := s.findMember(addMemberReq)
+	if err == nil {
+		err = s.store.SetTeamMember(teamID, member.UserID, member.Role)
+	}
+	if errors.Is(err, database.ErrLastTeamAdmin) {
+		err = errors.New("A team needs at least one admin.")
+	}
+	if err != nil {
+		s.renderTeams(w, r, user.ID, err.Error())
+		return
+	}
+
+	s.renderTeams(w, r, user.ID, "")
+}
+
+func (s *ApiRouter) handleRemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, err := getIntParam(r, "teamID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	userID, err := getIntParam(r, "userID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+
+	err = s.store.RemoveTeamMember(teamID, userID)
+	if errors.Is(err, database.ErrLastTeamAdmin) {
+		s.renderTeams(w, r, user.ID, "A team needs at least one admin.")
+		return
+	} else if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderTeams(w, r, user.ID, "")
+}
+
+func (s *ApiRouter) teams(userID int) (TeamsResponse, error) {
+	teams, err := s.store.GetTeams(userID)
+	if err != nil {
+		return TeamsResponse{}, err
+	}
+	return TeamsResponse{Teams: teams, Roles: types.MemberRoles, UserID: userID}, nil
+}
+
+func (s *ApiRouter) renderTeams(w http.ResponseWriter, r *http.Request, userID int, errMsg string) {
+	response, err := s.teams(userID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	response.Error = errMsg
+
+	tmpl, err := template.ParseFS(templates.Templates, "workspace/teams.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, response)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// handleGetSharedBoard shows a board to anyone with its public link. The
+// page is read-only and does not follow live changes.
+func (s *ApiRouter) handleGetSharedBoard(w http.ResponseWriter, r *http.Request) {
+	board, err := s.store.GetSharedBoard(chi.URLParam(r, "token"))
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "workspace/sharedBoard.html", "workspace/boardColumns.html", "workspace/cardDraggable.html", "workspace/card.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, BoardResponse{Board: board, Role: types.MemberViewer})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
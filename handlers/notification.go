@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_api/chat"
+	"go_api/mail"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+const (
+	notificationInterval = time.Minute
+	notificationPageSize = 50
+	// notificationEmailBatch caps the emails sent on one run of the worker.
+	notificationEmailBatch = 50
+	// Assigned cards due within dueSoonDays days from today get a reminder.
+	dueSoonDays = 1
+	// Chat users may mention people chatMentionRateLimit times per
+	// chatMentionRateWindow.
+	chatMentionRateLimit  = 10
+	chatMentionRateWindow = 10 * time.Minute
+)
+
+type NotificationsResponse struct {
+	Notifications []*types.Notification
+	Preferences   []*types.NotificationPreference
+	Notice        string
+}
+
+func notificationRoom(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// withUser lets through any logged in user and stores them in the request
+// context.
+func (s *ApiRouter) withUser(next http.Handler) http.Handler {
+	return s.withPermission(func(*types.User) bool { return true })(next)
+}
+
+func (s *ApiRouter) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.currentUser(r)
+
+	notifications, err := s.store.GetNotifications(user.ID, notificationPageSize)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	prefs, err := s.store.GetNotificationPreferences(user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "notification/notifications.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, NotificationsResponse{Notifications: notifications, Preferences: prefs})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// handleOpenNotification marks a notification as read and sends the user on
+// to what it is about.
+func (s *ApiRouter) handleOpenNotification(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.currentUser(r)
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	notification, err := s.store.MarkNotificationRead(id, user.ID)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+	s.publishNotificationCount(user.ID)
+
+	target := notification.URL
+	if target == "" {
+		target = "/notifications"
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func (s *ApiRouter) handleReadAllNotifications(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.currentUser(r)
+
+	if err := s.store.MarkAllNotificationsRead(user.ID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.publishNotificationCount(user.ID)
+
+	notifications, err := s.store.GetNotifications(user.ID, notificationPageSize)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderNotificationsPart(w, r, "notificationList", NotificationsResponse{Notifications: notifications})
+}
+
+func (s *ApiRouter) handleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	updatePrefsReq := new(types.UpdateNotificationPreferencesRequest)
+	if err := json.NewDecoder(r.Body).Decode(updatePrefsReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+
+	prefs := types.NewNotificationPreferences(updatePrefsReq)
+	if err := s.store.UpdateNotificationPreferences(user.ID, prefs); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderNotificationsPart(w, r, "notificationPreferences", NotificationsResponse{Preferences: prefs, Notice: "Saved."})
+}
+
+// handleNotificationBell renders the navbar bell with the unread count. It
+// is empty for anonymous visitors, who have no notifications.
+func (s *ApiRouter) handleNotificationBell(w http.ResponseWriter, r *http.Request) {
+	user, err := s.currentUser(r)
+	if err != nil || user == nil {
+		return
+	}
+
+	count, err := s.store.CountUnreadNotifications(user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/notificationBell.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, count)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// handleNotificationsLive subscribes the bell's websocket to the user's
+// unread count.
+func (s *ApiRouter) handleNotificationsLive(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.currentUser(r)
+	member := chat.Member{ID: user.ID, Name: user.FirstName + " " + user.LastName}
+	chat.ServeRoom(s.notifications, w, r, notificationRoom(user.ID), member)
+}
+
+func (s *ApiRouter) renderNotificationsPart(w http.ResponseWriter, r *http.Request, name string, data NotificationsResponse) {
+	tmpl, err := template.ParseFS(templates.Templates, "notification/notifications.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.ExecuteTemplate(w, name, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// notify stores a notification and updates the user's bell when it is
+// shown in the app. Failures are logged: a missed notification must not
+// fail the change that caused it.
+func (s *ApiRouter) notify(notification *types.Notification) {
+	if err := s.store.CreateNotification(notification); err != nil {
+		log.Printf("creating notification for user %d: %v", notification.UserID, err)
+		return
+	}
+	if notification.ID != 0 && notification.InApp {
+		s.publishNotificationCount(notification.UserID)
+	}
+}
+
+// notifyMentions notifies every user mentioned as @first.last in text, other
+// than its author. where completes "X mentioned you ...". Only users can be
+// mentioned; nil lets anyone be.
+func (s *ApiRouter) notifyMentions(users []*types.User, authorID *int, author string, text string, where string, url string, cardID *int) {
+	handles := types.Mentions(text)
+	if len(handles) == 0 {
+		return
+	}
+
+	if users == nil {
+		var err error
+		if users, err = s.store.GetUsersByHandles(handles); err != nil {
+			log.Println("Error loading users for mentions:", err)
+			return
+		}
+	}
+
+	for _, user := range users {
+		if authorID != nil && user.ID == *authorID {
+			continue
+		}
+		for _, handle := range handles {
+			if user.Handle() == handle {
+				notification := types.NewNotification(user.ID, types.NotifyMention, fmt.Sprintf("%s mentioned you %s", author, where), url)
+				notification.CardID = cardID
+				notification.ActorID = authorID
+				s.notify(notification)
+				break
+			}
+		}
+	}
+}
+
+// notifyChatMessage is called by the chat hub for every message. Visitors
+// who are not logged in cannot mention anyone, and the author is the
+// logged in sender rather than the nickname in the message.
+func (s *ApiRouter) notifyChatMessage(sender chat.Member, message []byte) {
+	if sender.ID == 0 {
+		return
+	}
+
+	var parsed struct {
+		ChatMessage string `json:"chat_message"`
+	}
+	if err := json.Unmarshal(message, &parsed); err != nil || len(types.Mentions(parsed.ChatMessage)) == 0 {
+		return
+	}
+
+	recent, err := s.store.CountMentionsSince(sender.ID, time.Now().UTC().Add(-chatMentionRateWindow))
+	if err != nil {
+		log.Println("Error counting chat mentions:", err)
+		return
+	}
+	if recent >= chatMentionRateLimit {
+		log.Printf("user %d mentioned too many people in the chat, skipping", sender.ID)
+		return
+	}
+
+	s.notifyMentions(nil, &sender.ID, sender.Name, parsed.ChatMessage, "in the chat", "/chat", nil)
+}
+
+func (s *ApiRouter) publishNotificationCount(userID int) {
+	count, err := s.store.CountUnreadNotifications(userID)
+	if err != nil {
+		log.Printf("counting notifications of user %d: %v", userID, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/notificationBell.html")
+	if err != nil {
+		log.Printf("publishing notifications of user %d: %v", userID, err)
+		return
+	}
+
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, "notificationCount", count); err != nil {
+		log.Printf("publishing notifications of user %d: %v", userID, err)
+		return
+	}
+	s.notifications.Publish(notificationRoom(userID), []byte(buf.String()))
+}
+
+// sendNotifications is the notification worker: it reminds assignees of
+// cards due soon and emails the notifications users asked to get by email.
+func (s *ApiRouter) sendNotifications() {
+	ticker := time.NewTicker(notificationInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.RemindDueCards(time.Now().UTC())
+		s.emailNotifications()
+	}
+}
+
+// RemindDueCards notifies the assignee of every open card due between now
+// and dueSoonDays days later. The worker calls it every minute; the
+// reminders are deduplicated per card and due date.
+func (s *ApiRouter) RemindDueCards(now time.Time) {
+	until := now.AddDate(0, 0, dueSoonDays)
+	cards, err := s.store.GetCardsDueBetween(now, until)
+	if err != nil {
+		log.Println("Error loading cards due soon:", err)
+		return
+	}
+
+	for _, card := range cards {
+		if card.DueOn == nil || card.AssigneeID == nil {
+			continue
+		}
+		due := card.DueOn.Format(dueOnLayout)
+		notification := types.NewNotification(*card.AssigneeID, types.NotifyDueSoon,
+			fmt.Sprintf("%q is due on %s", card.Name, card.DueOn.Format("Jan 2")), fmt.Sprintf("/workspace/%d", card.ID))
+		notification.CardID = &card.ID
+		// One reminder per due date: moving the date gets a new one.
+		notification.DedupeKey = fmt.Sprintf("due:%d:%s", card.ID, due)
+		s.notify(notification)
+	}
+}
+
+func (s *ApiRouter) emailNotifications() {
+	notifications, err := s.store.GetPendingEmailNotifications(notificationEmailBatch)
+	if err != nil {
+		log.Println("Error loading notifications to email:", err)
+		return
+	}
+
+	base := configuredSiteURL()
+	if base == "" && len(notifications) > 0 {
+		log.Println("SITE_URL is not set, leaving the links out of notification emails")
+	}
+	for _, notification := range notifications {
+		body := notification.Message + "\n"
+		if base != "" {
+			body = fmt.Sprintf("%s\n\n%s%s\n", notification.Message, base, notification.URL)
+		}
+		msg := mail.Message{
+			To:      []string{notification.Recipient},
+			Subject: strings.Join(strings.Fields(notification.Message), " "),
+			Body:    body,
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("emailing notification %d: %v", notification.ID, err)
+			continue
+		}
+		if err := s.store.MarkNotificationEmailed(notification.ID); err != nil {
+			log.Printf("marking notification %d as emailed: %v", notification.ID, err)
+		}
+	}
+}
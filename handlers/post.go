@@ -2,33 +2,126 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"go_api/markdown"
+	"go_api/pagination"
 	"go_api/types"
 
 	templates "go_api/templates"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxPostNameLength = 200
+	postExcerptLength = 200
+	postPageSize      = 10
+	// publishAtLayout matches the value of a datetime-local input.
+	publishAtLayout       = "2006-01-02T15:04"
+	postSchedulerInterval = time.Minute
 )
 
 type PostResponse struct {
-	Posts []*types.Post
-	Page  int
+	Posts       []*types.Post
+	NextURL     string
+	Title       string
+	Filter      types.PostFilter
+	Tags        []*types.Tag
+	Categories  []*types.Category
+	CanWrite    bool
+	CanModerate bool
+	CanAnalyze  bool
+}
+
+type PostDetailsResponse struct {
+	Post        *types.Post
+	Attachments AttachmentsResponse
+	CanEdit     bool
+}
+
+type PostEditorResponse struct {
+	Post       *types.Post
+	Categories []*types.Category
 }
 
 func (s *ApiRouter) handleGetPosts(w http.ResponseWriter, r *http.Request) {
-	pagination := r.Context().Value("pagination").(map[string]int)
-	page := pagination["page"]
+	filter := types.PostFilter{
+		Tag:      r.URL.Query().Get("tag"),
+		Category: r.URL.Query().Get("category"),
+	}
+
+	s.renderPosts(w, r, filter, "Blog")
+}
+
+func (s *ApiRouter) handleGetTagPosts(w http.ResponseWriter, r *http.Request) {
+	tag, err := s.store.GetTag(chi.URLParam(r, "slug"))
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
 
-	posts, err := s.store.GetPosts(page)
+	s.renderPosts(w, r, types.PostFilter{Tag: tag.Slug}, fmt.Sprintf("Posts tagged %s", tag.Name))
+}
+
+func (s *ApiRouter) handleGetCategoryPosts(w http.ResponseWriter, r *http.Request) {
+	category, err := s.store.GetCategory(chi.URLParam(r, "slug"))
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	s.renderPosts(w, r, types.PostFilter{Category: category.Slug}, fmt.Sprintf("Posts in %s", category.Name))
+}
+
+// renderPosts renders one page of the infinite-scroll post list. The first
+// page comes with the full layout, later ones are appended by htmx.
+func (s *ApiRouter) renderPosts(w http.ResponseWriter, r *http.Request, filter types.PostFilter, title string) {
+	params := pagination.FromContext(r.Context(), postPageSize)
+
+	user, _ := s.currentUser(r)
+
+	posts, next, err := s.store.GetPosts(params, user, filter)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+	for _, post := range posts {
+		post.Excerpt = markdown.Excerpt(post.ContentHTML, postExcerptLength)
+	}
+
 	data := PostResponse{
-		Posts: posts,
-		Page:  page + 1,
+		Posts:  posts,
+		Title:  title,
+		Filter: filter,
+	}
+	if next != nil {
+		query := r.URL.Query()
+		query.Set("cursor", next.Encode())
+		data.NextURL = r.URL.Path + "?" + query.Encode()
+	}
+	if user != nil {
+		data.CanWrite = user.CanWritePosts()
+		data.CanModerate = user.CanModerateComments()
+		data.CanAnalyze = user.CanViewAnalytics()
 	}
-	if page == 1 {
+	if params.First() {
+		if data.Tags, err = s.store.GetTags(); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if data.Categories, err = s.store.GetPublicCategories(); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
 
 		tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "posts/postsList.html", "posts/postsPaginated.html")
 		if err != nil {
@@ -58,43 +151,144 @@ func (s *ApiRouter) handleGetPosts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *ApiRouter) handleGetPost(w http.ResponseWriter, r *http.Request) {
-	id, err := getID(r)
+	post, err := s.findPost(r)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	// Ids and former slugs keep working but point at the current slug.
+	if chi.URLParam(r, "id") != post.Slug {
+		http.Redirect(w, r, postURL(post), http.StatusMovedPermanently)
+		return
+	}
+
+	canEdit := false
+	if user, err := s.currentUser(r); err == nil {
+		canEdit = user.CanEditPost(post)
+	}
+	if !canEdit && !post.IsPublic(time.Now().UTC()) {
+		s.handleNotFound(w, r)
+		return
+	}
+	if !canEdit {
+		s.recordView(r, post)
+	}
+
+	attachments, err := s.getAttachments(types.AttachmentPost, post.ID)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+	attachments.Editable = canEdit
 
-	post, err := s.store.GetPost(id)
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "post/postDetails.html", "attachment/attachments.html")
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "post/postDetails.html")
+	err = tmpl.Execute(w, PostDetailsResponse{
+		Post:        post,
+		Attachments: attachments,
+		CanEdit:     canEdit,
+	})
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+}
+
+func (s *ApiRouter) handleNewPost(w http.ResponseWriter, r *http.Request) {
+	s.renderPostEditor(w, r, &types.Post{Status: types.PostDraft, Tags: []*types.Tag{}})
+}
+
+func (s *ApiRouter) handleGetPostEditor(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	s.renderPostEditor(w, r, post)
+}
+
+func (s *ApiRouter) handleCreatePost(w http.ResponseWriter, r *http.Request) {
+	createPostReq := new(types.CreatePostRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(createPostReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
 
-	err = tmpl.Execute(w, post)
+	if err := validatePost(createPostReq.Name); err != nil {
+		s.renderBasicError(w, r, err.Error())
+		return
+	}
+
+	user, err := s.currentUser(r)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+
+	contentHTML, err := markdown.Render(createPostReq.Content)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	post, err := types.NewPost(strings.TrimSpace(createPostReq.Name), createPostReq.Content, contentHTML, &user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := setPostStatus(post, createPostReq.Status, createPostReq.PublishAt); err != nil {
+		s.renderBasicError(w, r, err.Error())
+		return
+	}
+	setPostTaxonomy(post, createPostReq.Category, createPostReq.Tags)
+
+	if err := s.store.CreatePost(post); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", postURL(post))
 }
 
-func (s *ApiRouter) handleDeletePost(w http.ResponseWriter, r *http.Request) {
-	id, err := getID(r)
+// handlePreviewPost renders the editor's Markdown exactly as it will be
+// stored, for the live preview next to the textarea.
+func (s *ApiRouter) handlePreviewPost(w http.ResponseWriter, r *http.Request) {
+	previewReq := new(types.PreviewPostRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(previewReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	contentHTML, err := markdown.Render(previewReq.Content)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	if err := s.store.DeletePost(id); err != nil {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(contentHTML))
+}
+
+func (s *ApiRouter) handleDeletePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeletePost(post.ID); err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
+	w.Header().Set("HX-Redirect", "/posts")
 }
 
 func (s *ApiRouter) handleEditPost(w http.ResponseWriter, r *http.Request) {
@@ -106,30 +300,172 @@ func (s *ApiRouter) handleEditPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := getID(r)
+	existing, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	if err := validatePost(updatePostReq.Name); err != nil {
+		s.renderBasicError(w, r, err.Error())
+		return
+	}
+
+	contentHTML, err := markdown.Render(updatePostReq.Content)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	post := types.UpdatePost(id, updatePostReq.Name, updatePostReq.Content)
+	post := types.UpdatePost(existing.ID, strings.TrimSpace(updatePostReq.Name), updatePostReq.Content, contentHTML)
+	post.PublishedAt = existing.PublishedAt
+
+	if err := setPostStatus(post, updatePostReq.Status, updatePostReq.PublishAt); err != nil {
+		s.renderBasicError(w, r, err.Error())
+		return
+	}
+	setPostTaxonomy(post, updatePostReq.Category, updatePostReq.Tags)
 
-	if err := s.store.UpdatePost(post); err != nil {
+	user, err := s.currentUser(r)
+	if err != nil {
 		s.handleError(w, r, err)
 		return
-	} else {
-		post, err := s.store.GetPost(post.ID)
-		tmpl, err := template.ParseFS(templates.Templates, "post/postRow.html")
+	}
+
+	if err := s.store.UpdatePost(post, &user.ID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", postURL(post))
+}
+
+// getEditablePost loads the post named in the URL and checks that the
+// current user may change it. It writes the response itself when not.
+func (s *ApiRouter) getEditablePost(w http.ResponseWriter, r *http.Request) (*types.Post, bool) {
+	post, err := s.findPost(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return nil, false
+	}
+
+	user, err := s.currentUser(r)
+	if err != nil || !user.CanEditPost(post) {
+		permissionDenied(w)
+		return nil, false
+	}
+
+	return post, true
+}
+
+// findPost resolves the {id} URL parameter, which is either a numeric post
+// id or a current or former slug.
+func (s *ApiRouter) findPost(r *http.Request) (*types.Post, error) {
+	param := chi.URLParam(r, "id")
+	if id, err := strconv.Atoi(param); err == nil {
+		return s.store.GetPost(id)
+	}
+	return s.store.GetPostBySlug(param)
+}
+
+func postURL(post *types.Post) string {
+	return "/posts/" + url.PathEscape(post.Slug)
+}
+
+func (s *ApiRouter) renderPostEditor(w http.ResponseWriter, r *http.Request, post *types.Post) {
+	categories, err := s.store.GetCategories()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "post/postEditor.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, PostEditorResponse{
+		Post:       post,
+		Categories: categories,
+	})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) renderBasicError(w http.ResponseWriter, r *http.Request, message string) {
+	tmpl, err := template.ParseFS(templates.Templates, "ui/basicError.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, message)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func validatePost(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("Title is required.")
+	}
+	if len(name) > maxPostNameLength {
+		return fmt.Errorf("Title must be at most %d characters.", maxPostNameLength)
+	}
+	return nil
+}
+
+// setPostStatus applies the status and publish time picked in the editor.
+// Publish times are entered and shown in UTC.
+func setPostStatus(post *types.Post, status string, publishAt string) error {
+	postStatus := types.PostStatus(status)
+	if status == "" {
+		postStatus = types.PostDraft
+	}
+	if !postStatus.Valid() {
+		return fmt.Errorf("Unknown status %q.", status)
+	}
+
+	var at *time.Time
+	if publishAt != "" {
+		parsed, err := time.ParseInLocation(publishAtLayout, publishAt, time.UTC)
 		if err != nil {
-			s.handleError(w, r, err)
-			return
+			return errors.New("Publish time is not valid.")
 		}
+		at = &parsed
+	}
 
-		err = tmpl.Execute(w, post)
+	return post.SetStatus(postStatus, at, time.Now().UTC())
+}
+
+// setPostTaxonomy applies the category name and comma separated tags
+// entered in the editor. A blank category clears it.
+func setPostTaxonomy(post *types.Post, category string, tags string) {
+	post.Category = nil
+	if c := types.NewCategory(category); c.Slug != "" {
+		post.Category = c
+	}
+	post.Tags = types.ParseTags(tags)
+}
+
+// publishScheduledPosts flips scheduled posts to published once their time
+// has come. Listings already show due posts, so a minute of lag is harmless.
+func (s *ApiRouter) publishScheduledPosts() {
+	ticker := time.NewTicker(postSchedulerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := s.store.PublishScheduledPosts(time.Now().UTC())
 		if err != nil {
-			s.handleError(w, r, err)
-			return
+			log.Println("Error publishing scheduled posts:", err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("Published %d scheduled posts", count)
 		}
 	}
-
 }
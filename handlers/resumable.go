@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_api/storage"
+	"go_api/types"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Resumable uploads follow the core tus 1.0 protocol: POST creates an
+// upload, HEAD reports how many bytes the server has, and PATCH appends a
+// chunk at that offset. Chunks are appended to a part file on disk and the
+// finished file is verified and moved into the blob store as a card
+// attachment. Uploads are held to the same size and content type limits as
+// card attachments sent in one request.
+//
+// Part files live on the local disk of the server that created the upload,
+// and the requests writing one are serialised in memory. Unlike attachments
+// in the blob store, an upload can therefore only be resumed against that
+// same server: running several replicas needs sticky routing for
+// /uploads/{uploadID}, or part files moved into shared storage.
+const (
+	tusVersion             = "1.0.0"
+	resumableUploadsDir    = "./tmp/uploads"
+	staleUploadAge         = 24 * time.Hour
+	uploadCleanupInterval  = time.Hour
+	statusChecksumMismatch = 460
+)
+
+var (
+	errChecksumMismatch = errors.New("checksum mismatch")
+	errUnsupportedType  = errors.New("unsupported file type")
+)
+
+// uploadLocks serialises the requests that write to one upload, so two
+// PATCHes racing for the same offset cannot both append their chunk.
+var uploadLocks = &keyedMutex{locks: map[string]*keyedLock{}}
+
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	holders int
+}
+
+// lock blocks until key is free and returns the function that frees it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	l := m.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.holders++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (s *ApiRouter) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if _, err := s.store.GetCard(id); err != nil {
+		WriteJSON(w, http.StatusNotFound, ApiError{Error: err.Error()})
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		s.handleError(w, r, errors.New("invalid Upload-Length"))
+		return
+	}
+	if limit := types.AttachmentRules[types.AttachmentCard].MaxSize; size > limit {
+		WriteJSON(w, http.StatusRequestEntityTooLarge, ApiError{Error: fmt.Sprintf("upload is larger than %d MB", limit>>20)})
+		return
+	}
+
+	metadata := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	fileName := filepath.Base(metadata["filename"])
+	if fileName == "." || fileName == "/" {
+		fileName = "upload"
+	}
+	checksum := strings.ToLower(metadata["checksum"])
+	if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		s.handleError(w, r, errors.New("metadata must include a hex encoded sha256 checksum"))
+		return
+	}
+
+	upload, err := types.NewUpload(id, fileName, size, checksum)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := os.MkdirAll(resumableUploadsDir, os.ModePerm); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if err := os.WriteFile(uploadPartPath(upload.ID), nil, 0644); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.CreateUpload(upload); err != nil {
+		os.Remove(uploadPartPath(upload.ID))
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Location", fmt.Sprintf("/workspace/%d/uploads/%s", id, upload.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *ApiRouter) handleHeadUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := s.getCardUpload(r)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *ApiRouter) handlePatchUpload(w http.ResponseWriter, r *http.Request) {
+	unlock := uploadLocks.lock(chi.URLParam(r, "uploadID"))
+	defer unlock()
+
+	// Read the upload once the lock is held, so its offset includes the
+	// chunk of any PATCH that went before.
+	upload, err := s.getCardUpload(r)
+	if err != nil {
+		WriteJSON(w, http.StatusNotFound, ApiError{Error: err.Error()})
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		WriteJSON(w, http.StatusUnsupportedMediaType, ApiError{Error: "expected application/offset+octet-stream"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		WriteJSON(w, http.StatusConflict, ApiError{Error: fmt.Sprintf("upload is at offset %d", upload.Offset)})
+		return
+	}
+
+	written, copyErr := appendUploadChunk(upload, r.Body)
+	// Persist whatever arrived, even if the client went away mid-chunk, so
+	// the next HEAD tells it where to resume.
+	if written > 0 {
+		upload.Offset += written
+		if err := s.store.UpdateUploadOffset(upload.ID, upload.Offset); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+	if copyErr != nil {
+		s.handleError(w, r, copyErr)
+		return
+	}
+
+	// Reject a file of the wrong type as soon as its first bytes arrive,
+	// rather than after the client has sent all of it.
+	if offset == 0 && written > 0 {
+		if err := s.checkUploadType(upload); errors.Is(err, errUnsupportedType) {
+			WriteJSON(w, http.StatusUnsupportedMediaType, ApiError{Error: err.Error()})
+			return
+		} else if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	if upload.Complete() {
+		if err := s.completeUpload(upload); err == errChecksumMismatch {
+			WriteJSON(w, statusChecksumMismatch, ApiError{Error: err.Error()})
+			return
+		} else if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ApiRouter) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	unlock := uploadLocks.lock(chi.URLParam(r, "uploadID"))
+	defer unlock()
+
+	upload, err := s.getCardUpload(r)
+	if err != nil {
+		WriteJSON(w, http.StatusNotFound, ApiError{Error: err.Error()})
+		return
+	}
+
+	if err := s.discardUpload(upload); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ApiRouter) getCardUpload(r *http.Request) (*types.Upload, error) {
+	id, err := getID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.store.GetUpload(chi.URLParam(r, "uploadID"))
+	if err != nil {
+		return nil, err
+	}
+	if upload.CardID != id {
+		return nil, fmt.Errorf("upload %s not found", upload.ID)
+	}
+
+	return upload, nil
+}
+
+// appendUploadChunk writes body at the upload's persisted offset. The part
+// file is truncated first, dropping any bytes of an earlier chunk that made
+// it to disk without their offset being recorded.
+func appendUploadChunk(upload *types.Upload, body io.Reader) (int64, error) {
+	f, err := os.OpenFile(uploadPartPath(upload.ID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(upload.Offset); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(f, io.LimitReader(body, upload.Size-upload.Offset))
+	if syncErr := f.Sync(); err == nil {
+		err = syncErr
+	}
+	return written, err
+}
+
+// checkUploadType sniffs the start of the part file and discards the upload
+// when card attachments may not have that content type.
+func (s *ApiRouter) checkUploadType(upload *types.Upload) error {
+	f, err := os.Open(uploadPartPath(upload.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	contentType, err := sniffContentType(f)
+	if err != nil {
+		return err
+	}
+	if !types.AttachmentRules[types.AttachmentCard].Allows(contentType) {
+		if err := s.discardUpload(upload); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w %s", errUnsupportedType, contentType)
+	}
+	return nil
+}
+
+// completeUpload verifies the checksum of a fully received upload and turns
+// it into a card attachment.
+func (s *ApiRouter) completeUpload(upload *types.Upload) error {
+	f, err := os.Open(uploadPartPath(upload.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != upload.Checksum {
+		s.discardUpload(upload)
+		return errChecksumMismatch
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	contentType, err := sniffContentType(f)
+	if err != nil {
+		return err
+	}
+	if !types.AttachmentRules[types.AttachmentCard].Allows(contentType) {
+		s.discardUpload(upload)
+		return fmt.Errorf("%w %s", errUnsupportedType, contentType)
+	}
+
+	key := storage.ContentKey("attachments", upload.Checksum, "")
+	if err := s.putBlob(key, upload.Checksum, f, upload.Size, contentType); err != nil {
+		return err
+	}
+
+	attachment := types.NewAttachment(types.AttachmentCard, upload.CardID, key, upload.FileName, contentType, upload.Size)
+	if err := s.store.CreateAttachment(attachment); err != nil {
+		return err
+	}
+
+	return s.discardUpload(upload)
+}
+
+func (s *ApiRouter) discardUpload(upload *types.Upload) error {
+	if err := os.Remove(uploadPartPath(upload.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.store.DeleteUpload(upload.ID)
+}
+
+// cleanupUploads periodically removes stale uploads and orphaned part files.
+func (s *ApiRouter) cleanupUploads() {
+	ticker := time.NewTicker(uploadCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.CleanupUploads(time.Now().UTC())
+	}
+}
+
+// CleanupUploads removes the uploads that stopped receiving chunks
+// staleUploadAge before now, together with their part files. It then removes
+// part files as old that have no upload left, such as those of the uploads
+// of a deleted card.
+func (s *ApiRouter) CleanupUploads(now time.Time) {
+	staleBefore := now.Add(-staleUploadAge)
+
+	uploads, err := s.store.GetStaleUploads(staleBefore)
+	if err != nil {
+		log.Println("Error listing stale uploads:", err)
+		return
+	}
+	for _, upload := range uploads {
+		unlock := uploadLocks.lock(upload.ID)
+		if err := s.discardUpload(upload); err != nil {
+			log.Println("Error removing stale upload:", err)
+		}
+		unlock()
+	}
+
+	parts, err := filepath.Glob(filepath.Join(resumableUploadsDir, "*.part"))
+	if err != nil {
+		log.Println("Error listing part files:", err)
+		return
+	}
+	for _, part := range parts {
+		info, err := os.Stat(part)
+		if err != nil || info.ModTime().After(staleBefore) {
+			continue
+		}
+		// A part file that old with its upload still stored belongs to an
+		// upload the loop above failed to remove; leave it for the next run.
+		if _, err := s.store.GetUpload(strings.TrimSuffix(filepath.Base(part), ".part")); err == nil {
+			continue
+		}
+		if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+			log.Println("Error removing orphaned part file:", err)
+		}
+	}
+}
+
+func uploadPartPath(id string) string {
+	return filepath.Join(resumableUploadsDir, id+".part")
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header, a comma
+// separated list of "key base64(value)" pairs.
+func parseUploadMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(value)
+	}
+	return metadata
+}
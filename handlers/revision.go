@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"go_api/diff"
+	"go_api/markdown"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+type PostHistoryResponse struct {
+	Post      *types.Post
+	Revisions []*types.PostRevision
+	From      *types.PostRevision
+	To        *types.PostRevision
+	Diff      []diff.Line
+	Changed   bool
+}
+
+// handleGetPostHistory lists the post's revisions and diffs the two picked
+// with the from and to query parameters, defaulting to the last change.
+func (s *ApiRouter) handleGetPostHistory(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := s.store.GetPostRevisions(post.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := PostHistoryResponse{
+		Post:      post,
+		Revisions: revisions,
+	}
+
+	if len(revisions) > 0 {
+		data.To = revisions[0]
+		data.From = revisions[0]
+		if len(revisions) > 1 {
+			data.From = revisions[1]
+		}
+
+		if data.From, err = findRevision(revisions, r.URL.Query().Get("from"), data.From); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if data.To, err = findRevision(revisions, r.URL.Query().Get("to"), data.To); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		data.Diff = diff.Lines(revisionText(data.From), revisionText(data.To))
+		data.Changed = diff.Changed(data.Diff)
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "post/postHistory.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// handleRestorePostRevision saves an old revision's title and content as
+// the post's current state, which adds a new revision on top.
+func (s *ApiRouter) handleRestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.getEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	revisionID, err := getIntParam(r, "revisionID")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	revision, err := s.store.GetPostRevision(revisionID)
+	if err != nil || revision.PostID != existing.ID {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	contentHTML, err := markdown.Render(revision.Content)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	post := types.UpdatePost(existing.ID, revision.Name, revision.Content, contentHTML)
+	post.Status = existing.Status
+	post.PublishedAt = existing.PublishedAt
+	post.Category = existing.Category
+	post.Tags = existing.Tags
+
+	user, err := s.currentUser(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.UpdatePost(post, &user.ID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/posts/%d/history", post.ID))
+}
+
+func findRevision(revisions []*types.PostRevision, idStr string, fallback *types.PostRevision) (*types.PostRevision, error) {
+	if idStr == "" {
+		return fallback, nil
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid revision given %s", idStr)
+	}
+
+	for _, revision := range revisions {
+		if revision.ID == id {
+			return revision, nil
+		}
+	}
+
+	return nil, fmt.Errorf("revision %d not found", id)
+}
+
+// revisionText is what the history diffs: the title as a heading line
+// followed by the Markdown source.
+func revisionText(revision *types.PostRevision) string {
+	return "# " + revision.Name + "\n\n" + revision.Content
+}
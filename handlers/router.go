@@ -1,20 +1,25 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strconv"
 
+	"go_api/analytics"
 	"go_api/chat"
 	"go_api/database"
+	"go_api/mail"
+	"go_api/pagination"
+	"go_api/storage"
 	"go_api/templates"
+	"go_api/types"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,27 +29,61 @@ import (
 type ApiRouter struct {
 	listenAddress string
 	store         database.Methods
+	blobs         storage.BlobStore
+	mailer        mail.Sender
+	views         *analytics.Counter
+	// trustedProxies are the proxies whose X-Forwarded-For is believed.
+	trustedProxies []netip.Prefix
+	chat           *chat.Hub
+	boards         *chat.RoomHub
+	notifications  *chat.RoomHub
 }
 
 type ApiError struct {
 	Error string `json:"error"`
 }
 
-func NewAPIServer(listenAddress string, store database.Methods) *ApiRouter {
-	return &ApiRouter{
-		listenAddress: listenAddress,
-		store:         store,
+func NewAPIServer(listenAddress string, store database.Methods, blobs storage.BlobStore, mailer mail.Sender) *ApiRouter {
+	s := &ApiRouter{
+		listenAddress:  listenAddress,
+		store:          store,
+		blobs:          blobs,
+		mailer:         mailer,
+		views:          analytics.NewCounter(),
+		trustedProxies: analytics.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES")),
+		boards:         chat.NewRoomHub(renderBoardPresence),
+		notifications:  chat.NewRoomHub(nil),
 	}
+	s.chat = chat.NewHub(s.notifyChatMessage)
+	return s
 }
 
+// Run starts the hubs and background workers and serves the site.
 func (s *ApiRouter) Run() {
+	flag.Parse()
+	go s.chat.Run()
+	go s.boards.Run()
+	go s.notifications.Run()
+	go s.cleanupUploads()
+	go s.collectGarbage()
+	go s.publishScheduledPosts()
+	go s.flushViews()
+	go s.sendNotifications()
+
+	log.Println("JSON API server running on port:", s.listenAddress)
+
+	http.ListenAndServe(s.listenAddress, s.Routes())
+}
+
+// Routes builds the site's router. Live updates need the hubs Run starts.
+func (s *ApiRouter) Routes() http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost", "https://localhost", "http://87.106.122.212", "https://87.106.122.212"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"},
+		ExposedHeaders:   []string{"Link", "Location", "Tus-Resumable", "Upload-Offset", "Upload-Length"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -53,16 +92,11 @@ func (s *ApiRouter) Run() {
 	workDir, _ := os.Getwd()
 	filesDir := http.Dir(filepath.Join(workDir, "/static"))
 	router.Handle("/static/*", http.StripPrefix("/static/", cacheControlWrapper(http.FileServer(filesDir))))
-	router.HandleFunc("/robots.txt", robotsHandler)
+	router.HandleFunc("/robots.txt", s.handleRobots)
+	router.Get("/sitemap.xml", s.handleSitemap)
 	router.NotFound(s.handleNotFound)
 
-	flag.Parse()
-	hub := chat.NewHub()
-	go hub.Run()
-
-	router.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		chat.ServeWs(hub, w, r)
-	})
+	router.HandleFunc("/ws", s.handleChatWs)
 	router.Get("/", s.handleHome)
 	router.Get("/auth/login", s.handleLoginGet)
 	router.Post("/auth/login", s.handleLoginPost)
@@ -71,7 +105,7 @@ func (s *ApiRouter) Run() {
 	router.Get("/auth/register", s.handleRegisterGet)
 	router.Route("/users", func(r chi.Router) {
 		r.Use(JWTAuthMiddleware(s.store))
-		r.Get("/", s.handleGetUsers)
+		r.With(pagination.Middleware(userPageSize)).Get("/", s.handleGetUsers)
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", s.handleGetUser)
 			r.Get("/edit", s.handlgeGetUserEditRow)
@@ -82,29 +116,108 @@ func (s *ApiRouter) Run() {
 		})
 	})
 
+	router.With(pagination.Middleware(searchPageSize)).Get("/search", s.handleSearch)
+	router.Route("/comments", func(r chi.Router) {
+		r.Use(s.withCommentModerator)
+		r.Get("/", s.handleGetModerationQueue)
+		r.Post("/{commentID}/approve", s.handleApproveComment)
+		r.Post("/{commentID}/reject", s.handleRejectComment)
+		r.Delete("/{commentID}", s.handleDeleteComment)
+	})
 	router.Route("/chat", func(r chi.Router) {
 		r.Get("/", s.handleChat)
 		r.Post("/login", s.handleChatLogin)
 	})
 
+	router.Get("/notifications/bell", s.handleNotificationBell)
+	router.Route("/notifications", func(r chi.Router) {
+		r.Use(s.withUser)
+		r.Get("/", s.handleGetNotifications)
+		r.Get("/live", s.handleNotificationsLive)
+		r.Post("/read", s.handleReadAllNotifications)
+		r.Put("/preferences", s.handleUpdateNotificationPreferences)
+		r.Get("/{id}", s.handleOpenNotification)
+	})
+
+	router.Get("/shared/boards/{token}", s.handleGetSharedBoard)
 	router.Route("/workspace", func(r chi.Router) {
-		r.Get("/", s.handleGetCards)
-		r.Post("/reorder", s.handleReorderCards)
+		r.Use(JWTAuthMiddleware(s.store))
+		r.Use(s.withUser)
+		r.Get("/", s.handleGetBoards)
+		r.Post("/boards", s.handleCreateBoard)
+		r.Post("/import", s.handleImportBoard)
+		r.Post("/teams", s.handleCreateTeam)
+		r.Route("/teams/{teamID}/members", func(r chi.Router) {
+			r.Use(s.withTeamRole(types.MemberAdmin))
+			r.Post("/", s.handleAddTeamMember)
+			r.Delete("/{userID}", s.handleRemoveTeamMember)
+		})
+		r.Route("/boards/{id}", func(r chi.Router) {
+			r.With(s.withBoardRole(types.MemberViewer)).Get("/", s.handleGetBoard)
+			r.With(s.withBoardRole(types.MemberEditor)).Post("/columns", s.handleCreateColumn)
+			r.With(s.withBoardRole(types.MemberEditor)).Post("/columns/{columnID}/cards", s.handleCreateCard)
+			r.With(s.withBoardRole(types.MemberEditor)).Post("/labels", s.handleCreateLabel)
+			r.With(s.withBoardRole(types.MemberEditor)).Post("/move", s.handleMoveCard)
+			r.With(s.withBoardRole(types.MemberViewer)).Get("/live", s.handleBoardLive)
+			r.With(s.withBoardRole(types.MemberViewer)).Get("/export.json", s.handleExportBoardJSON)
+			r.With(s.withBoardRole(types.MemberViewer)).Get("/export.csv", s.handleExportBoardCSV)
+			r.Group(func(r chi.Router) {
+				r.Use(s.withBoardRole(types.MemberAdmin))
+				r.Get("/members", s.handleGetBoardMembers)
+				r.Post("/members", s.handleAddBoardMember)
+				r.Delete("/members/{userID}", s.handleRemoveBoardMember)
+				r.Post("/share", s.handleShareBoard)
+				r.Delete("/share", s.handleUnshareBoard)
+			})
+		})
 		r.Route("/{id}", func(r chi.Router) {
-			r.Get("/", s.handleGetCard)
-			r.Get("/edit", s.handleEditCard)
-			r.Get("/delete", s.handleDeleteCard)
+			r.With(s.withCardRole(types.MemberViewer)).Get("/", s.handleGetCard)
+			r.With(s.withCardRole(types.MemberViewer)).Get("/attachments", s.handleGetCardAttachments)
+			r.Group(func(r chi.Router) {
+				r.Use(s.withCardRole(types.MemberEditor))
+				r.Put("/", s.handleEditCard)
+				r.Delete("/", s.handleDeleteCard)
+				r.Post("/checklist", s.handleCreateChecklistItem)
+				r.Post("/toggle", s.handleToggleChecklistItem)
+				r.Post("/comments", s.handleCreateCardComment)
+				r.Post("/attachments", s.handleUploadCardAttachment)
+				r.Delete("/attachments/{attachmentID}", s.handleDeleteCardAttachment)
+				r.Post("/uploads", s.handleCreateUpload)
+				r.Route("/uploads/{uploadID}", func(r chi.Router) {
+					r.Head("/", s.handleHeadUpload)
+					r.Patch("/", s.handlePatchUpload)
+					r.Delete("/", s.handleDeleteUpload)
+				})
+			})
 		})
 	})
 
 	router.Route("/posts", func(r chi.Router) {
-		r.Use(PaginationMiddleware)
+		r.Use(pagination.Middleware(postPageSize))
 		r.Get("/", s.handleGetPosts)
+		r.With(s.withPostAuthor).Get("/new", s.handleNewPost)
+		r.With(s.withPostAuthor).Post("/", s.handleCreatePost)
+		r.With(s.withPostAuthor).Post("/preview", s.handlePreviewPost)
+		r.With(s.withAnalyticsViewer).Get("/analytics", s.handleGetAnalytics)
+		r.Get("/feed.atom", s.handleAtomFeed)
+		r.Get("/feed.rss", s.handleRSSFeed)
+		r.Get("/tags/{slug}", s.handleGetTagPosts)
+		r.Get("/categories/{slug}", s.handleGetCategoryPosts)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", s.handleGetPost)
+			r.With(s.withPostAuthor).Get("/edit", s.handleGetPostEditor)
+			r.With(s.withPostAuthor).Put("/", s.handleEditPost)
+			r.With(s.withPostAuthor).Delete("/", s.handleDeletePost)
+			r.Get("/comments", s.handleGetComments)
+			r.Post("/comments", s.handleCreateComment)
+			r.With(s.withPostAuthor).Get("/history", s.handleGetPostHistory)
+			r.With(s.withPostAuthor).Post("/revisions/{revisionID}/restore", s.handleRestorePostRevision)
+			r.With(s.withPostAuthor).Post("/attachments", s.handleUploadPostAttachment)
+			r.With(s.withPostAuthor).Delete("/attachments/{attachmentID}", s.handleDeletePostAttachment)
+		})
 	})
 
-	log.Println("JSON API server running on port:", s.listenAddress)
-
-	http.ListenAndServe(s.listenAddress, router)
+	return router
 }
 
 func cacheControlWrapper(h http.Handler) http.Handler {
@@ -114,31 +227,6 @@ func cacheControlWrapper(h http.Handler) http.Handler {
 	})
 }
 
-func robotsHandler(w http.ResponseWriter, r *http.Request) {
-	robotsTxt := []byte("User-agent: *\nDisallow: /private/")
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write(robotsTxt)
-}
-
-func PaginationMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("calling pagination middleware")
-		pageStr := r.URL.Query().Get("page")
-		page, err := strconv.Atoi(pageStr)
-		if err != nil || page < 1 {
-			page = 1
-		}
-
-		pagination := map[string]int{
-			"page": page,
-		}
-
-		ctx := context.WithValue(r.Context(), "pagination", pagination)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-}
-
 func (s *ApiRouter) handleHome(w http.ResponseWriter, r *http.Request) {
 	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "ui/home.html")
 	if err != nil {
@@ -182,10 +270,14 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 }
 
 func getID(r *http.Request) (int, error) {
-	idStr := chi.URLParam(r, "id")
+	return getIntParam(r, "id")
+}
+
+func getIntParam(r *http.Request, name string) (int, error) {
+	idStr := chi.URLParam(r, name)
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return id, fmt.Errorf("invalid id given %s", idStr)
+		return id, fmt.Errorf("invalid %s given %s", name, idStr)
 	}
 	return id, nil
 }
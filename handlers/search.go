@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_api/pagination"
+	"go_api/types"
+
+	templates "go_api/templates"
+)
+
+const (
+	maxSearchQueryLength = 200
+	searchPageSize       = 10
+)
+
+type SearchResponse struct {
+	Query   string
+	Results []*types.SearchResult
+	First   bool
+	NextURL string
+}
+
+// handleSearch serves the search page. Requests made by htmx, from the live
+// search box or the infinite scroll, only get the results fragment.
+func (s *ApiRouter) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := pagination.FromContext(r.Context(), searchPageSize)
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if runes := []rune(query); len(runes) > maxSearchQueryLength {
+		query = string(runes[:maxSearchQueryLength])
+	}
+
+	data := SearchResponse{
+		Query:   query,
+		Results: []*types.SearchResult{},
+		First:   params.First(),
+	}
+
+	if query != "" {
+		results, more, err := s.search(query, params)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		data.Results = results
+
+		// Results are ranked, so they page by offset rather than by cursor.
+		if more {
+			next := r.URL.Query()
+			next.Set("page", strconv.Itoa(params.Page+1))
+			data.NextURL = "/search?" + next.Encode()
+		}
+	}
+
+	files := []string{"ui/base.html", "ui/navbar.html", "search/search.html", "search/searchResults.html"}
+	if r.Header.Get("HX-Request") == "true" {
+		files = []string{"search/searchResults.html"}
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, files...)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// search runs the query against the searchable content. Only posts are
+// indexed so far and the store ranks them; other kinds can plug in by
+// returning types.SearchResult from their own store method, merged by rank.
+func (s *ApiRouter) search(query string, params pagination.Params) ([]*types.SearchResult, bool, error) {
+	return s.store.SearchPosts(query, params)
+}
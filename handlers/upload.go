@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"time"
+
+	"go_api/images"
+	"go_api/storage"
+	"go_api/types"
 )
 
+const maxImageUploadSize = 10 << 20
+
 type UploadHandler interface {
 	handleUploadUserImages(w http.ResponseWriter, r *http.Request) error
 }
@@ -26,78 +34,99 @@ func (s *ApiRouter) handleUploadUserImages(w http.ResponseWriter, r *http.Reques
 		s.handleError(w, r, err)
 		return
 	}
-	if err := r.ParseMultipartForm(32 * 1024 * 1024); err != nil {
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize)
+	if err := r.ParseMultipartForm(maxImageUploadSize); err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
 	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		s.handleError(w, r, errors.New("no file uploaded"))
+		return
+	}
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			s.handleError(w, r, err)
-			return
-		}
-		defer file.Close()
+	file, err := files[0].Open()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	defer file.Close()
 
-		buff := make([]byte, 512)
-		_, err = file.Read(buff)
-		if err != nil {
-			s.handleError(w, r, err)
-			return
-		}
+	filetype, err := sniffContentType(file)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if filetype != "image/jpeg" && filetype != "image/png" {
+		s.handleError(w, r, fmt.Errorf("unsupported file type %s", filetype))
+		return
+	}
 
-		filetype := http.DetectContentType(buff)
-		if filetype != "image/jpeg" && filetype != "image/png" && filetype != "image/jpg" {
-			s.handleError(w, r, err)
-			return
-		}
+	// Avatars are addressed by the hash of the original upload, so uploading
+	// the same picture twice reuses the renditions already stored.
+	hash := sha256.New()
+	format, renditions, err := images.Process(io.TeeReader(file, hash))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
 
-		err = os.MkdirAll("./static/uploads", os.ModePerm)
+	imageKey := storage.ContentKey("avatars", hex.EncodeToString(hash.Sum(nil)), images.Extension(format))
+	contentType := "image/" + format
+	for _, rendition := range renditions {
+		key := images.VariantPath(imageKey, rendition.Variant.Name)
+		digest := sha256.Sum256(rendition.Data)
+		err := s.putBlob(key, hex.EncodeToString(digest[:]), bytes.NewReader(rendition.Data), int64(len(rendition.Data)), contentType)
 		if err != nil {
 			s.handleError(w, r, err)
 			return
 		}
+	}
 
-		_, err = file.Seek(0, io.SeekStart)
-		if err != nil {
-			s.handleError(w, r, err)
-			return
-		}
+	// The previous renditions are released here and deleted later by the
+	// garbage collector once nothing references them.
+	user.ImageKey = imageKey
+	if err := s.store.UpdateUserImage(user); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
 
-		newFileName := fmt.Sprintf("./static/uploads/%d%s", time.Now().UnixNano(), filepath.Ext(fileHeader.Filename))
-		f, err := os.Create(newFileName)
-		if err != nil {
-			s.handleError(w, r, err)
-			return
-		}
-		if len(user.ImageURL) > 2 {
-			err := os.Remove(user.ImageURL[1:])
-			if err != nil {
-				s.handleError(w, r, err)
-				return
-			}
-		}
-		user.ImageURL = "." + newFileName
-		s.store.UpdateUserImage(user)
+	s.resolveUserImages(user)
+	w.Header().Set("Content-Type", "text/plain")
+	_, err = w.Write([]byte(user.ImageURLs["large"]))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
 
-		defer f.Close()
-		user, err := s.store.GetUser(id)
-		if err != nil {
-			s.handleError(w, r, err)
-			return
-		}
-		_, err = io.Copy(f, file)
-		if err != nil {
-			s.handleError(w, r, err)
-			return
+// sniffContentType detects the type of an uploaded file from its first 512
+// bytes, then rewinds it so it can be read from the start again.
+func sniffContentType(file multipart.File) (string, error) {
+	buff := make([]byte, 512)
+	n, err := file.Read(buff)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buff[:n]), nil
+}
+
+// resolveUserImages fills in the public URL of every avatar rendition.
+func (s *ApiRouter) resolveUserImages(users ...*types.User) {
+	for _, user := range users {
+		if user == nil || user.ImageKey == "" {
+			continue
 		}
-		w.Header().Set("Content-Type", "text/plain")
-		_, err = w.Write([]byte(user.ImageURL))
-		if err != nil {
-			s.handleError(w, r, err)
-			return
+		user.ImageURLs = make(map[string]string, len(images.Variants))
+		for i, key := range images.VariantKeys(user.ImageKey) {
+			user.ImageURLs[images.Variants[i].Name] = s.blobs.URL(key)
 		}
 	}
 }
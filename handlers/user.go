@@ -5,22 +5,50 @@ import (
 	"html/template"
 	"net/http"
 
+	"go_api/pagination"
 	"go_api/types"
 
 	templates "go_api/templates"
 )
 
+const userPageSize = 25
+
+type UsersResponse struct {
+	Users   []*types.User
+	NextURL string
+}
+
+// handleGetUsers renders one page of the infinite-scroll users table. The
+// first page comes with the full layout, later ones are appended by htmx.
 func (s *ApiRouter) handleGetUsers(w http.ResponseWriter, r *http.Request) {
-	
-	users, err := s.store.GetUsers()
+	params := pagination.FromContext(r.Context(), userPageSize)
+
+	users, next, err := s.store.GetUsersPage(params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.resolveUserImages(users...)
+
+	data := UsersResponse{Users: users}
+	if next != nil {
+		query := r.URL.Query()
+		query.Set("cursor", next.Encode())
+		data.NextURL = r.URL.Path + "?" + query.Encode()
+	}
+
+	files := []string{"ui/base.html", "ui/navbar.html", "user/usersList.html", "user/usersPaginated.html", "user/userRow.html"}
+	if !params.First() {
+		files = []string{"user/usersPaginated.html", "user/userRow.html"}
+	}
 
-	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "user/usersList.html", "user/userRow.html")
+	tmpl, err := template.ParseFS(templates.Templates, files...)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	err = tmpl.Execute(w, users)
+	err = tmpl.Execute(w, data)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
@@ -39,6 +67,7 @@ func (s *ApiRouter) handleGetUser(w http.ResponseWriter, r *http.Request) {
 		s.handleError(w, r, err)
 		return
 	}
+	s.resolveUserImages(user)
 
 	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "user/userDetails.html")
 	if err != nil {
@@ -89,6 +118,7 @@ func (s *ApiRouter) handleEditUser(w http.ResponseWriter, r *http.Request) {
 		return
 	} else {
 		user, err := s.store.GetUser(user.ID)
+		s.resolveUserImages(user)
 		tmpl, err := template.ParseFS(templates.Templates, "user/userRow.html")
 		if err != nil {
 			s.handleError(w, r, err)
@@ -111,6 +141,7 @@ func (s *ApiRouter) handlgeGetUserEditRow(w http.ResponseWriter, r *http.Request
 		return
 	}
 	user, err := s.store.GetUser(id)
+	s.resolveUserImages(user)
 
 	tmpl, err := template.ParseFS(templates.Templates, "user/userEditRow.html")
 	if err != nil {
@@ -133,6 +164,7 @@ func (s *ApiRouter) HandleGetUserRow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user, err := s.store.GetUser(id)
+	s.resolveUserImages(user)
 
 	tmpl, err := template.ParseFS(templates.Templates, "user/userRow.html")
 	if err != nil {
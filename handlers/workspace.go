@@ -2,24 +2,230 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
+	"time"
 
+	"go_api/database"
 	"go_api/types"
 
 	templates "go_api/templates"
 )
 
-func (s *ApiRouter) handleGetCards(w http.ResponseWriter, r *http.Request) {
-	cards, err := s.store.GetCards()
+// dueOnLayout matches the value of a date input.
+const dueOnLayout = "2006-01-02"
 
-	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "workspace/cardsList.html", "workspace/cardDraggable.html", "workspace/card.html")
+// CardFormResponse feeds the editable title and content of a card, shown
+// both in the board's modal and on the card page.
+type CardFormResponse struct {
+	Card       *types.Card
+	Users      []*types.User
+	Labels     []*types.Label
+	Priorities []types.CardPriority
+	// CanEdit is false for viewers, who get the form disabled.
+	CanEdit bool
+	Error   string
+	Notice  string
+}
+
+type CardDetailsResponse struct {
+	CardFormResponse
+	Activity    ActivityResponse
+	Attachments AttachmentsResponse
+}
+
+type BoardsResponse struct {
+	Boards []*types.Board
+	Teams  TeamsResponse
+}
+
+type BoardResponse struct {
+	Board  *types.Board
+	Labels LabelsResponse
+	// UserID is the viewer, for the "my cards" filter; 0 on a shared board.
+	UserID int
+	// Role is the viewer's role; viewers get the board without its forms.
+	Role   types.MemberRole
+	Notice string
+}
+
+type LabelsResponse struct {
+	BoardID int
+	Labels  []*types.Label
+	Colors  []string
+	Error   string
+}
+
+func (s *ApiRouter) handleGetBoards(w http.ResponseWriter, r *http.Request) {
+	user, _ := s.currentUser(r)
+
+	boards, err := s.store.GetBoards(user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	teams, err := s.teams(user.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "workspace/boards.html", "workspace/teams.html")
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	err = tmpl.Execute(w, cards)
+	err = tmpl.Execute(w, BoardsResponse{Boards: boards, Teams: teams})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) handleCreateBoard(w http.ResponseWriter, r *http.Request) {
+	createBoardReq := new(types.CreateBoardRequest)
+	if err := json.NewDecoder(r.Body).Decode(createBoardReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	board, err := types.NewBoard(createBoardReq.Name)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.setBoardOwner(r, board, createBoardReq.TeamID); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.CreateBoard(board); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/workspace/boards/%d", board.ID))
+}
+
+// setBoardOwner makes a new board the user's own, or the team's when one is
+// given and the user may create boards for it.
+func (s *ApiRouter) setBoardOwner(r *http.Request, board *types.Board, teamID string) error {
+	user, err := s.currentUser(r)
+	if err != nil {
+		return err
+	}
+
+	if teamID == "" {
+		board.OwnerID = &user.ID
+		return nil
+	}
+
+	id, err := strconv.Atoi(teamID)
+	if err != nil {
+		return fmt.Errorf("invalid team %q", teamID)
+	}
+	role, err := s.store.GetTeamRole(id, user.ID)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(types.MemberEditor) {
+		return errors.New("You cannot create boards for this team.")
+	}
+	board.TeamID = &id
+	return nil
+}
+
+func (s *ApiRouter) handleGetBoard(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	board, err := s.store.GetBoard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	labels, err := s.store.GetLabels(id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	user, _ := s.currentUser(r)
+	response := BoardResponse{
+		Board:  board,
+		Labels: LabelsResponse{BoardID: id, Labels: labels, Colors: types.LabelColors},
+		UserID: user.ID,
+		Role:   memberRole(r),
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "workspace/board.html", "workspace/boardLabels.html", "workspace/boardColumns.html", "workspace/cardDraggable.html", "workspace/card.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, response)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) handleCreateColumn(w http.ResponseWriter, r *http.Request) {
+	createColumnReq := new(types.CreateColumnRequest)
+	if err := json.NewDecoder(r.Body).Decode(createColumnReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	column, err := types.NewColumn(id, createColumnReq.Name, 0)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.store.CreateColumn(column); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardColumns(w, r, id, http.StatusOK, "")
+	s.publishBoard(id)
+}
+
+// renderBoardColumns renders the sortable columns of a board as they are
+// stored, replacing whatever order the browser currently shows.
+func (s *ApiRouter) renderBoardColumns(w http.ResponseWriter, r *http.Request, boardID int, status int, notice string) {
+	board, err := s.store.GetBoard(boardID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "workspace/boardColumns.html", "workspace/cardDraggable.html", "workspace/card.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(status)
+	err = tmpl.Execute(w, BoardResponse{Board: board, Role: memberRole(r), Notice: notice})
 	if err != nil {
 		s.handleError(w, r, err)
 		return
@@ -33,25 +239,102 @@ func (s *ApiRouter) handleGetCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	card, err := s.store.GetCard(id)
+	card, err := s.store.GetCardTree(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	form, err := s.cardForm(r, card)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	activity, err := s.cardActivity(r, card.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		details := CardDetailsResponse{CardFormResponse: form, Activity: activity}
+		s.renderCard(w, r, details, "card/cardModal.html", "card/cardForm.html", "card/cardChecklist.html", "card/cardActivity.html", "card/cardEvent.html")
+		return
+	}
+
+	attachments, err := s.getAttachments(types.AttachmentCard, card.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	attachments.Editable = form.CanEdit
+
+	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "card/cardDetails.html", "card/cardForm.html", "card/cardChecklist.html", "card/cardActivity.html", "card/cardEvent.html", "attachment/attachments.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, CardDetailsResponse{
+		CardFormResponse: form,
+		Activity:         activity,
+		Attachments:      attachments,
+	})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *ApiRouter) handleCreateCard(w http.ResponseWriter, r *http.Request) {
+	createCardReq := new(types.CreateCardRequest)
+	if err := json.NewDecoder(r.Body).Decode(createCardReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	tmpl, err := template.ParseFS(templates.Templates, "ui/base.html", "ui/navbar.html", "card/cardDetails.html")
+	columnID, err := getIntParam(r, "columnID")
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	err = tmpl.Execute(w, card)
+	board, err := s.store.GetBoard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+	if board.Column(columnID) == nil {
+		s.handleError(w, r, fmt.Errorf("column %d not found on board %d", columnID, id))
+		return
+	}
+
+	card, err := types.NewCard(columnID, createCardReq.Name, createCardReq.Content, nil)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+
+	if err := s.store.CreateCard(card, s.actorID(r)); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardColumns(w, r, id, http.StatusOK, "")
+	s.publishBoard(id)
 }
 
+// handleDeleteCard answers the board's modal with the updated columns and
+// sends the card page back to the parent card or the board. Sub-cards are
+// only deleted along with the card when ?cascade=true is given; otherwise a
+// card that has them is left alone.
 func (s *ApiRouter) handleDeleteCard(w http.ResponseWriter, r *http.Request) {
 	id, err := getID(r)
 	if err != nil {
@@ -59,17 +342,35 @@ func (s *ApiRouter) handleDeleteCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.store.DeleteCard(id); err != nil {
+	card, err := s.store.GetCard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if err := s.store.DeleteCard(id, cascade); errors.Is(err, database.ErrCardHasChildren) {
+		s.handleError(w, r, errors.New("This card has sub-cards. Delete them first or delete them together with the card."))
+		return
+	} else if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
+	s.publishBoard(card.BoardID)
+	if r.Header.Get("HX-Target") == "board-columns" {
+		s.renderBoardColumns(w, r, card.BoardID, http.StatusOK, "")
+		return
+	}
+	if card.Parent != nil {
+		w.Header().Set("HX-Redirect", fmt.Sprintf("/workspace/%d", *card.Parent))
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/workspace/boards/%d", card.BoardID))
 }
 
 func (s *ApiRouter) handleEditCard(w http.ResponseWriter, r *http.Request) {
-
 	updateCardReq := new(types.UpdateCardRequest)
-
 	if err := json.NewDecoder(r.Body).Decode(updateCardReq); err != nil {
 		s.handleError(w, r, err)
 		return
@@ -81,51 +382,262 @@ func (s *ApiRouter) handleEditCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	card := types.UpdateCard(id, updateCardReq.Name, updateCardReq.Content, updateCardReq.Position, updateCardReq.Parent)
+	existing, err := s.store.GetCard(id)
+	if err != nil {
+		s.handleNotFound(w, r)
+		return
+	}
 
-	if err := s.store.UpdateCard(card); err != nil {
+	card, err := types.UpdateCard(id, updateCardReq.Name, updateCardReq.Content)
+	if err == nil {
+		err = setCardDetails(card, updateCardReq)
+	}
+	if err == nil {
+		err = s.checkAssignee(existing, card)
+	}
+	if err != nil {
+		existing.Name = updateCardReq.Name
+		existing.Content = updateCardReq.Content
+		form, formErr := s.cardForm(r, existing)
+		if formErr != nil {
+			s.handleError(w, r, formErr)
+			return
+		}
+		form.Error = err.Error()
+		s.renderCard(w, r, form, "card/cardForm.html")
+		return
+	}
+
+	if err := s.store.UpdateCard(card, s.actorID(r)); err != nil {
 		s.handleError(w, r, err)
 		return
-	} else {
-		card, err := s.store.GetCard(card.ID)
-		tmpl, err := template.ParseFS(templates.Templates, "card/cardRow.html")
-		if err != nil {
-			s.handleError(w, r, err)
+	}
+	s.notifyAssignee(r, existing, card)
+
+	card, err = s.store.GetCardTree(id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.publishCard(card)
+
+	form, err := s.cardForm(r, card)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	form.Notice = "Saved."
+
+	// A rename or a new assignee shows up in the activity list next to the
+	// form straight away.
+	activity, err := s.cardActivity(r, card.ID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	activity.OOB = true
+
+	tmpl, err := template.ParseFS(templates.Templates, "card/cardForm.html", "card/cardActivity.html", "card/cardEvent.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "cardForm.html", form); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if err := tmpl.ExecuteTemplate(w, "cardEvents", activity); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+// notifyAssignee tells the card's new assignee about it, unless they
+// assigned it to themselves.
+func (s *ApiRouter) notifyAssignee(r *http.Request, previous *types.Card, card *types.Card) {
+	if card.AssigneeID == nil || previous.AssignedTo(*card.AssigneeID) {
+		return
+	}
+
+	assigner := "Someone"
+	if user, err := s.currentUser(r); err == nil && user != nil {
+		if user.ID == *card.AssigneeID {
 			return
 		}
+		assigner = user.FirstName + " " + user.LastName
+	}
+
+	notification := types.NewNotification(*card.AssigneeID, types.NotifyAssigned,
+		fmt.Sprintf("%s assigned you to %q", assigner, card.Name), fmt.Sprintf("/workspace/%d", card.ID))
+	notification.CardID = &card.ID
+	s.notify(notification)
+}
+
+// checkAssignee refuses to assign a card to someone who cannot open its
+// board. An assignee who lost access since can stay on the card.
+func (s *ApiRouter) checkAssignee(previous *types.Card, card *types.Card) error {
+	if card.AssigneeID == nil || previous.AssignedTo(*card.AssigneeID) {
+		return nil
+	}
+
+	role, err := s.store.GetBoardRole(previous.BoardID, *card.AssigneeID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return errors.New("Cards can only be assigned to members of their board.")
+	}
+	return nil
+}
 
-		err = tmpl.Execute(w, card)
+// setCardDetails copies the assignee, due date, priority and labels of an
+// update request onto the card.
+func setCardDetails(card *types.Card, req *types.UpdateCardRequest) error {
+	if req.AssigneeID != "" {
+		assigneeID, err := strconv.Atoi(req.AssigneeID)
 		if err != nil {
-			s.handleError(w, r, err)
-			return
+			return fmt.Errorf("invalid assignee %q", req.AssigneeID)
+		}
+		card.AssigneeID = &assigneeID
+	}
+
+	if req.DueOn != "" {
+		dueOn, err := time.ParseInLocation(dueOnLayout, req.DueOn, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid due date %q", req.DueOn)
 		}
+		card.DueOn = &dueOn
+	}
+
+	card.Priority = types.PriorityNone
+	if req.Priority != "" {
+		card.Priority = types.CardPriority(req.Priority)
+	}
+	if !card.Priority.Valid() {
+		return fmt.Errorf("invalid priority %q", req.Priority)
+	}
+
+	card.Labels = []*types.Label{}
+	for _, value := range req.Labels {
+		if value == "" {
+			continue
+		}
+		labelID, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid label %q", value)
+		}
+		card.Labels = append(card.Labels, &types.Label{ID: labelID})
+	}
+	return nil
+}
+
+// cardForm gathers the choices offered by the card form: the members of the
+// board, who the card can be assigned to, and the labels of the board.
+func (s *ApiRouter) cardForm(r *http.Request, card *types.Card) (CardFormResponse, error) {
+	users, err := s.store.GetBoardUsers(card.BoardID)
+	if err != nil {
+		return CardFormResponse{}, err
+	}
+
+	labels, err := s.store.GetLabels(card.BoardID)
+	if err != nil {
+		return CardFormResponse{}, err
+	}
+
+	return CardFormResponse{
+		Card:       card,
+		Users:      users,
+		Labels:     labels,
+		Priorities: types.CardPriorities,
+		CanEdit:    memberRole(r).Allows(types.MemberEditor),
+	}, nil
+}
+
+func (s *ApiRouter) handleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	createLabelReq := new(types.CreateLabelRequest)
+	if err := json.NewDecoder(r.Body).Decode(createLabelReq); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	id, err := getID(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
 	}
 
+	response := LabelsResponse{BoardID: id, Colors: types.LabelColors}
+	label, err := types.NewLabel(id, createLabelReq.Name, createLabelReq.Color)
+	if err == nil {
+		err = s.store.CreateLabel(label)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if response.Labels, err = s.store.GetLabels(id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	tmpl, err := template.ParseFS(templates.Templates, "workspace/boardLabels.html")
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	err = tmpl.Execute(w, response)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
 }
 
-func (s *ApiRouter) handleReorderCards(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+func (s *ApiRouter) renderCard(w http.ResponseWriter, r *http.Request, data any, files ...string) {
+	tmpl, err := template.ParseFS(templates.Templates, files...)
 	if err != nil {
 		s.handleError(w, r, err)
+		return
 	}
 
-	cards, _ := r.PostForm["item"]
-	var orderedCards []*types.Card
-	if ordered, err := s.store.ReorderCards(cards); err != nil {
+	err = tmpl.Execute(w, data)
+	if err != nil {
 		s.handleError(w, r, err)
 		return
-	} else {
-		orderedCards = ordered
 	}
-	tmpl, err := template.ParseFS(templates.Templates, "workspace/cardDraggable.html", "workspace/card.html")
+}
+
+// handleMoveCard stores a single drag. When someone else changed the board
+// since the browser rendered it, the move is dropped and the current layout
+// is sent back with 409 Conflict so the client can swap it in.
+func (s *ApiRouter) handleMoveCard(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	err = tmpl.Execute(w, orderedCards)
+	if err := r.ParseForm(); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	move, err := types.ParseMoveCardRequest(r.PostForm)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
+
+	err = s.store.MoveCard(id, move, s.actorID(r))
+	if errors.Is(err, database.ErrBoardChanged) {
+		s.renderBoardColumns(w, r, id, http.StatusConflict, "This board was changed by someone else. Your last move was not saved.")
+		return
+	} else if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderBoardColumns(w, r, id, http.StatusOK, "")
+	s.publishBoard(id)
 }
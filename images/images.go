@@ -0,0 +1,187 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MaxDimension is the largest width or height accepted for an upload.
+	MaxDimension = 4096
+	// MaxPixels caps width*height so a small file can't expand into a huge
+	// bitmap once decoded.
+	MaxPixels = 4096 * 4096
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image dimensions are too large")
+)
+
+type Variant struct {
+	Name string
+	Size int
+}
+
+// Variants are the square renditions generated for every avatar upload.
+var Variants = []Variant{
+	{Name: "thumb", Size: 100},
+	{Name: "large", Size: 400},
+}
+
+type Rendition struct {
+	Variant Variant
+	Data    []byte
+}
+
+// Process decodes an uploaded image, crops it to a centered square and
+// re-encodes one rendition per entry in Variants. Re-encoding drops any
+// EXIF/GPS metadata carried by the original file.
+func Process(r io.Reader) (string, []Rendition, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, ErrUnsupportedFormat
+	}
+	if format != "jpeg" && format != "png" {
+		return "", nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 ||
+		cfg.Width > MaxDimension || cfg.Height > MaxDimension ||
+		cfg.Width*cfg.Height > MaxPixels {
+		return "", nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, err
+	}
+
+	square := cropSquare(src.Bounds())
+	renditions := make([]Rendition, 0, len(Variants))
+	for _, v := range Variants {
+		var buf bytes.Buffer
+		if err := encode(&buf, resize(src, square, v.Size), format); err != nil {
+			return "", nil, err
+		}
+		renditions = append(renditions, Rendition{Variant: v, Data: buf.Bytes()})
+	}
+
+	return format, renditions, nil
+}
+
+// Extension returns the file extension used when storing a rendition of
+// the given format.
+func Extension(format string) string {
+	if format == "png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// VariantPath inserts the variant name before the extension, so
+// "uploads/123.jpg" becomes "uploads/123_thumb.jpg".
+func VariantPath(path, variant string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(path, ext), variant, ext)
+}
+
+// VariantKeys returns the blob key of every rendition of the avatar stored
+// under key, in the order of Variants. Avatars uploaded before renditions
+// existed are bare file names such as "123.png" whose original is the only
+// file stored, so every variant falls back to it.
+func VariantKeys(key string) []string {
+	if key == "" {
+		return nil
+	}
+	legacy := !strings.Contains(key, "/")
+	keys := make([]string, 0, len(Variants))
+	for _, v := range Variants {
+		if legacy {
+			keys = append(keys, key)
+		} else {
+			keys = append(keys, VariantPath(key, v.Name))
+		}
+	}
+	return keys
+}
+
+func encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "png":
+		return png.Encode(w, img)
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return ErrUnsupportedFormat
+}
+
+func cropSquare(b image.Rectangle) image.Rectangle {
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x0, y0, x0+side, y0+side)
+}
+
+// resize scales the square rect of src to size x size by averaging every
+// source pixel that falls inside each destination pixel. When the source is
+// smaller than the target it falls back to nearest-neighbour upscaling.
+func resize(src image.Image, rect image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	side := rect.Dx()
+
+	for y := 0; y < size; y++ {
+		sy0 := rect.Min.Y + y*side/size
+		sy1 := rect.Min.Y + (y+1)*side/size
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < size; x++ {
+			sx0 := rect.Min.X + x*side/size
+			sx1 := rect.Min.X + (x+1)*side/size
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: uint8(a / n),
+			})
+		}
+	}
+
+	return dst
+}
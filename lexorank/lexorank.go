@@ -0,0 +1,95 @@
+// Package lexorank generates string keys that sort between two others, so an
+// item can move in an ordered list by rewriting only its own key.
+//
+// Keys are base 36 fractions written with the digits 0-9a-z and compared as
+// plain byte strings. A key never ends in "0", which guarantees there is
+// always room for another key below it.
+package lexorank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var (
+	ErrInvalidKey   = errors.New("invalid rank key")
+	ErrInvalidRange = errors.New("lower rank must sort before upper rank")
+)
+
+// Between returns a key that sorts strictly after lower and strictly before
+// upper. An empty lower means the start of the list and an empty upper the
+// end, so Between("", "") is a key for the first item of an empty list.
+func Between(lower, upper string) (string, error) {
+	if lower != "" && !Valid(lower) || upper != "" && !Valid(upper) {
+		return "", ErrInvalidKey
+	}
+	if upper != "" && lower >= upper {
+		return "", ErrInvalidRange
+	}
+	return midpoint(lower, upper), nil
+}
+
+// After returns a key for appending after last, e.g. a new card at the
+// bottom of a column.
+func After(last string) (string, error) {
+	return Between(last, "")
+}
+
+// Valid reports whether key is a well formed, non-empty rank key.
+func Valid(key string) bool {
+	if key == "" || strings.HasSuffix(key, "0") {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// midpoint assumes lower < upper, with an empty upper standing for 1.
+func midpoint(lower, upper string) string {
+	// Skip the common prefix, padding lower with zeros as needed.
+	n := 0
+	for n < len(upper) && digitAt(lower, n) == strings.IndexByte(digits, upper[n]) {
+		n++
+	}
+	if n > 0 {
+		rest := ""
+		if n < len(lower) {
+			rest = lower[n:]
+		}
+		return upper[:n] + midpoint(rest, upper[n:])
+	}
+
+	low := digitAt(lower, 0)
+	high := len(digits)
+	if upper != "" {
+		high = strings.IndexByte(digits, upper[0])
+	}
+	if high-low > 1 {
+		return string(digits[(low+high)/2])
+	}
+
+	// The first digits are consecutive. If upper continues past its first
+	// digit, that digit alone already sorts between the two keys.
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+
+	rest := ""
+	if len(lower) > 1 {
+		rest = lower[1:]
+	}
+	return string(digits[low]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return strings.IndexByte(digits, key[i])
+}
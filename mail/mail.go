@@ -0,0 +1,125 @@
+// Package mail sends plain text email through a pluggable Sender.
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("mail header contains a line break")
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSender builds the sender selected by the MAIL_SENDER environment
+// variable: "smtp" to deliver through SMTP_HOST, anything else to log
+// messages instead of sending them.
+func NewSender() (Sender, error) {
+	switch os.Getenv("MAIL_SENDER") {
+	case "smtp":
+		return NewSMTPSender(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	case "", "log":
+		return &LogSender{Logger: log.Default()}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", os.Getenv("MAIL_SENDER"))
+	}
+}
+
+// LogSender writes messages to a logger. It is the default for local
+// development, where there is no mail server.
+type LogSender struct {
+	Logger *log.Logger
+}
+
+func (s *LogSender) Send(msg Message) error {
+	s.Logger.Printf("mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(config SMTPConfig) (*SMTPSender, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, errors.New("smtp sender needs a host and a from address")
+	}
+	if config.Port == "" {
+		config.Port = "587"
+	}
+
+	sender := &SMTPSender{
+		addr: config.Host + ":" + config.Port,
+		from: config.From,
+	}
+	if config.Username != "" {
+		sender.auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	return sender, nil
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	data, err := msg.Bytes(s.from, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, msg.To, data)
+}
+
+// Bytes renders the message as an RFC 5322 document with a UTF-8 plain
+// text body.
+func (m Message) Bytes(from string, date time.Time) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, errors.New("mail has no recipients")
+	}
+	for _, value := range append([]string{from, m.Subject}, m.To...) {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
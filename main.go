@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"go_api/database"
 	server "go_api/handlers"
+	"go_api/mail"
+	"go_api/storage"
 	"log"
 )
 
@@ -16,6 +18,16 @@ func main() {
 
 	fmt.Printf("%+v\n", Store)
 
-	server := server.NewAPIServer(":3000", Store)
+	blobs, err := storage.NewBlobStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailer, err := mail.NewSender()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := server.NewAPIServer(":3000", Store, blobs, mailer)
 	server.Run()
 }
@@ -0,0 +1,77 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// HighlightStyle is the chroma style static/css/highlight.css was generated
+// from. Code blocks are rendered with CSS classes rather than inline styles
+// so the sanitizer doesn't need to allow style attributes.
+const HighlightStyle = "github"
+
+var renderer = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Table,
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(HighlightStyle),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+		),
+	),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+	),
+)
+
+var policy = newPolicy()
+
+var excerptPolicy = bluemonday.StrictPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)).OnElements("pre", "code", "span", "div")
+	p.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render converts CommonMark (plus tables, strikethrough, autolinks and task
+// lists) to HTML, highlights fenced code blocks and runs the result through
+// an allow-list sanitizer. The output is safe to embed in a page as is.
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
+
+// Excerpt returns the first n characters of the text of rendered HTML, cut
+// at a word boundary.
+func Excerpt(renderedHTML string, n int) string {
+	text := html.UnescapeString(excerptPolicy.Sanitize(renderedHTML))
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+
+	runes := []rune(text)[:n]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
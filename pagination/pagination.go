@@ -0,0 +1,107 @@
+// Package pagination parses paging parameters from requests and encodes
+// the opaque cursors used for keyset pagination. It pages posts, users and
+// search results. Board cards are not paged: a column is always shown whole,
+// since a dragged card is ranked between whichever neighbours it lands on.
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const MaxSize = 50
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page in a listing ordered by (Time, ID).
+// The next page starts right after it.
+type Cursor struct {
+	Time time.Time
+	ID   int
+}
+
+// Encode returns the cursor as an opaque, URL safe token.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.Time.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Decode(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var nanos int64
+	var id int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Time: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// Params are the paging parameters of a request. Keyset listings use
+// After; listings that can't, such as ranked search results, use Page.
+type Params struct {
+	Size  int
+	After *Cursor
+	Page  int
+}
+
+// First reports whether the request is for the first page.
+func (p Params) First() bool {
+	return p.After == nil && p.Page <= 1
+}
+
+type contextKey struct{}
+
+// Middleware reads the cursor, page and size query parameters into the
+// request context. Sizes are capped at MaxSize and default to defaultSize;
+// malformed cursors fall back to the first page.
+func Middleware(defaultSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			params := Params{Size: defaultSize, Page: 1}
+
+			if size, err := strconv.Atoi(query.Get("size")); err == nil && size > 0 {
+				params.Size = min(size, MaxSize)
+			}
+			if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+				params.Page = page
+			}
+			if token := query.Get("cursor"); token != "" {
+				if cursor, err := Decode(token); err == nil {
+					params.After = cursor
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), contextKey{}, params)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromContext returns the parameters stored by Middleware, or the first
+// page of defaultSize when there are none.
+func FromContext(ctx context.Context, defaultSize int) Params {
+	if params, ok := ctx.Value(contextKey{}).(Params); ok {
+		return params
+	}
+	return Params{Size: defaultSize, Page: 1}
+}
+
+// Trim cuts a result fetched with Size+1 rows down to the page and reports
+// whether more rows follow.
+func Trim[T any](items []T, size int) ([]T, bool) {
+	if len(items) > size {
+		return items[:size], true
+	}
+	return items, false
+}
@@ -0,0 +1,106 @@
+// Minimal tus-style client used for large workspace attachments. Files are
+// sent in chunks and the upload URL is remembered in localStorage, so a
+// dropped connection or a page reload resumes from the last offset the
+// server acknowledged instead of starting over.
+(function () {
+  const CHUNK_SIZE = 5 * 1024 * 1024;
+  const MAX_RETRIES = 5;
+  const TUS_HEADERS = { "Tus-Resumable": "1.0.0" };
+
+  async function sha256Hex(file) {
+    const digest = await crypto.subtle.digest("SHA-256", await file.arrayBuffer());
+    return Array.from(new Uint8Array(digest))
+      .map(function (b) { return b.toString(16).padStart(2, "0"); })
+      .join("");
+  }
+
+  function storageKey(endpoint, file) {
+    return ["upload", endpoint, file.name, file.size, file.lastModified].join(":");
+  }
+
+  async function create(endpoint, file) {
+    const checksum = await sha256Hex(file);
+    const filename = btoa(unescape(encodeURIComponent(file.name)));
+    const res = await fetch(endpoint, {
+      method: "POST",
+      headers: Object.assign({
+        "Upload-Length": String(file.size),
+        "Upload-Metadata": "filename " + filename + ",checksum " + btoa(checksum),
+      }, TUS_HEADERS),
+    });
+    if (res.status !== 201) {
+      throw new Error((await res.json()).error);
+    }
+    return res.headers.get("Location");
+  }
+
+  async function currentOffset(location) {
+    const res = await fetch(location, { method: "HEAD", headers: TUS_HEADERS });
+    if (!res.ok) {
+      return null;
+    }
+    return parseInt(res.headers.get("Upload-Offset"), 10);
+  }
+
+  function wait(ms) {
+    return new Promise(function (resolve) { setTimeout(resolve, ms); });
+  }
+
+  async function upload(endpoint, file, onProgress) {
+    const key = storageKey(endpoint, file);
+    let location = localStorage.getItem(key);
+    let offset = location ? await currentOffset(location) : null;
+
+    if (offset === null) {
+      location = await create(endpoint, file);
+      localStorage.setItem(key, location);
+      offset = 0;
+    }
+
+    let retries = 0;
+    while (offset < file.size) {
+      onProgress(offset / file.size * 100);
+      let res;
+      try {
+        res = await fetch(location, {
+          method: "PATCH",
+          headers: Object.assign({
+            "Upload-Offset": String(offset),
+            "Content-Type": "application/offset+octet-stream",
+          }, TUS_HEADERS),
+          body: file.slice(offset, offset + CHUNK_SIZE),
+        });
+      } catch (err) {
+        if (++retries > MAX_RETRIES) {
+          throw err;
+        }
+        await wait(1000 * retries);
+        offset = await currentOffset(location);
+        if (offset === null) {
+          throw err;
+        }
+        continue;
+      }
+
+      if (res.status === 409) {
+        offset = await currentOffset(location);
+        if (offset === null || ++retries > MAX_RETRIES) {
+          throw new Error("upload offset mismatch");
+        }
+        continue;
+      }
+      if (!res.ok) {
+        localStorage.removeItem(key);
+        throw new Error((await res.json()).error);
+      }
+
+      retries = 0;
+      offset = parseInt(res.headers.get("Upload-Offset"), 10);
+    }
+
+    localStorage.removeItem(key);
+    onProgress(100);
+  }
+
+  window.resumableUpload = upload;
+})();
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore keeps uploaded files under opaque, slash separated keys such as
+// "avatars/123.jpg". Rows store the key and ask the store for a URL when
+// rendering, so the same data works whichever backend is configured.
+type BlobStore interface {
+	Put(key string, r io.Reader, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+	// List returns every blob whose key starts with prefix.
+	List(prefix string) ([]BlobInfo, error)
+}
+
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// NewBlobStore builds the store selected by the BLOB_STORE environment
+// variable: "s3" for an S3 compatible bucket, anything else for local disk.
+func NewBlobStore() (BlobStore, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+	case "", "local":
+		return NewLocalStore("./static/uploads", "/static/uploads"), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", os.Getenv("BLOB_STORE"))
+	}
+}
+
+// ContentKey builds the content addressed key for a blob from the hex
+// encoded SHA-256 of its data, e.g. "attachments/9f86d0...".
+func ContentKey(prefix, digest, ext string) string {
+	return prefix + "/" + digest + ext
+}
+
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -10,5 +10,12 @@ import (
 //go:embed posts/*
 //go:embed chat/*
 //go:embed workspace/*
+//go:embed post/*
+//go:embed card/*
+//go:embed attachment/*
+//go:embed search/*
+//go:embed comment/*
+//go:embed analytics/*
+//go:embed notification/*
 
 var Templates embed.FS
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go_api/analytics"
+)
+
+const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/119.0"
+
+func TestCounterDeduplicatesDailyVisitors(t *testing.T) {
+	now := time.Date(2023, 11, 21, 10, 0, 0, 0, time.UTC)
+	counter := analytics.NewCounterWithClock(func() time.Time { return now })
+
+	if !counter.Record(1, "10.0.0.1", browser) {
+		t.Error("Expected the first view to be counted")
+	}
+	if counter.Record(1, "10.0.0.1", browser) {
+		t.Error("Expected a repeat view on the same day not to be counted")
+	}
+	if !counter.Record(2, "10.0.0.1", browser) {
+		t.Error("Expected a view of another post to be counted")
+	}
+	if !counter.Record(1, "10.0.0.2", browser) {
+		t.Error("Expected another visitor to be counted")
+	}
+
+	now = now.Add(24 * time.Hour)
+	if !counter.Record(1, "10.0.0.1", browser) {
+		t.Error("Expected the visitor to be counted again the next day")
+	}
+
+	counts := map[int]int{}
+	for _, v := range counter.Drain() {
+		counts[v.PostID] += v.Views
+	}
+	if counts[1] != 3 || counts[2] != 1 {
+		t.Errorf("Expected 3 views of post 1 and 1 of post 2, but got %v", counts)
+	}
+
+	if len(counter.Drain()) != 0 {
+		t.Error("Expected Drain to empty the buffer")
+	}
+}
+
+func TestCounterIgnoresBots(t *testing.T) {
+	counter := analytics.NewCounter()
+
+	for _, ua := range []string{"", "Googlebot/2.1", "curl/8.0"} {
+		if counter.Record(1, "10.0.0.1", ua) {
+			t.Errorf("Expected user agent %q not to be counted", ua)
+		}
+	}
+}
+
+func TestCounterRequeue(t *testing.T) {
+	counter := analytics.NewCounter()
+	counter.Record(1, "10.0.0.1", browser)
+
+	views := counter.Drain()
+	counter.Requeue(views)
+	counter.Requeue(views)
+
+	drained := counter.Drain()
+	if len(drained) != 1 || drained[0].Views != 2 {
+		t.Errorf("Expected requeued views to add up to 2, but got %+v", drained)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	points := analytics.Sparkline([]int{0, 5, 10}, 100, 20)
+
+	expected := "0.0,20.0 50.0,10.0 100.0,0.0"
+	if points != expected {
+		t.Errorf("Expected points %q, but got %q", expected, points)
+	}
+
+	if flat := analytics.Sparkline([]int{0, 0}, 100, 20); flat != "0.0,20.0 100.0,20.0" {
+		t.Errorf("Expected a flat line along the bottom, but got %q", flat)
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go_api/analytics"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := analytics.ParseTrustedProxies("127.0.0.1, 10.0.0.0/8, not an address")
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"203.0.113.7:4321", "", "203.0.113.7"},
+		{"203.0.113.7:4321", "198.51.100.1", "203.0.113.7"},
+		{"127.0.0.1:4321", "198.51.100.1", "198.51.100.1"},
+		{"127.0.0.1:4321", "192.0.2.9, 198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"127.0.0.1:4321", "10.1.2.3", "10.1.2.3"},
+		{"127.0.0.1:4321", "", "127.0.0.1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/posts/1", nil)
+		r.RemoteAddr = test.remoteAddr
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if ip := analytics.ClientIP(r, trusted); ip != test.expected {
+			t.Errorf("Expected %s from %s forwarded for %q, but got %s", test.expected, test.remoteAddr, test.forwarded, ip)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if proxies := analytics.ParseTrustedProxies(""); len(proxies) != 0 {
+		t.Errorf("Expected no trusted proxies by default, but got %v", proxies)
+	}
+	if proxies := analytics.ParseTrustedProxies("::1, 192.168.0.0/16"); len(proxies) != 2 {
+		t.Errorf("Expected 2 trusted proxies, but got %v", proxies)
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	trusted := analytics.ParseTrustedProxies("10.0.0.0/8")
+
+	tests := []struct {
+		remoteAddr string
+		expected   bool
+	}{
+		{"10.1.2.3:4321", true},
+		{"203.0.113.7:4321", false},
+		{"not an address", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		if trusted := analytics.FromTrustedProxy(r, trusted); trusted != test.expected {
+			t.Errorf("Expected %s to be trusted to be %v, but got %v", test.remoteAddr, test.expected, trusted)
+		}
+	}
+}
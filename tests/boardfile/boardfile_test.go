@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/boardfile"
+	"go_api/types"
+)
+
+func sampleBoard() (*types.Board, []*types.Label) {
+	due := time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)
+	bug := &types.Label{ID: 1, Name: "bug", Color: "#eb5a46"}
+
+	board := &types.Board{
+		Name: "Launch",
+		Columns: []*types.Column{
+			{Name: "To do", Cards: []*types.Card{{
+				Name:     "Write docs",
+				Content:  "README, CHANGELOG",
+				DueOn:    &due,
+				Priority: types.PriorityHigh,
+				Labels:   []*types.Label{bug},
+				Children: []*types.Card{
+					{Name: "README", Done: true, Priority: types.PriorityNone},
+					{Name: "CHANGELOG", Priority: types.PriorityNone},
+				},
+			}}},
+			{Name: "Done", Cards: []*types.Card{}},
+		},
+	}
+	return board, []*types.Label{bug}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	out, err := boardfile.FromBoard(sampleBoard()).JSON()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	file, err := boardfile.ParseJSON(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if file.CardCount() != 3 {
+		t.Errorf("Expected 3 cards, but got %d", file.CardCount())
+	}
+
+	board, labels, err := file.Board()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if board.Name != "Launch" || len(board.Columns) != 2 || len(labels) != 1 {
+		t.Fatalf("Expected board Launch with 2 columns and 1 label, but got %+v and %d labels", board, len(labels))
+	}
+
+	card := board.Columns[0].Cards[0]
+	if card.Priority != types.PriorityHigh || card.DueOn == nil || card.DueOn.Format("2006-01-02") != "2023-11-30" {
+		t.Errorf("Expected priority and due date to survive, but got %q and %v", card.Priority, card.DueOn)
+	}
+	if len(card.Labels) != 1 || card.Labels[0].Name != "bug" {
+		t.Errorf("Expected the bug label, but got %v", card.Labels)
+	}
+	if len(card.Children) != 2 || !card.Children[0].Done || card.Children[1].Done {
+		t.Errorf("Expected two sub-cards with the first done, but got %+v", card.Children)
+	}
+}
+
+func TestParseJSONRejectsOtherVersions(t *testing.T) {
+	if _, err := boardfile.ParseJSON(strings.NewReader(`{"version": 2, "name": "x"}`)); err == nil {
+		t.Errorf("Expected an error for version 2, but got nil")
+	}
+}
+
+func TestBoardRejectsUnknownLabels(t *testing.T) {
+	file := &boardfile.File{
+		Version: boardfile.Version,
+		Name:    "Launch",
+		Columns: []*boardfile.Column{{Name: "To do", Cards: []*boardfile.Card{{Name: "x", Labels: []string{"missing"}}}}},
+	}
+	if _, _, err := file.Board(); err == nil {
+		t.Errorf("Expected an error for an unknown label, but got nil")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	out, err := boardfile.FromBoard(sampleBoard()).CSV()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, but got %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected a header and 3 rows, but got %d rows", len(rows))
+	}
+
+	expected := []string{"To do", "2", "1", "README", "", "true", "", "", ""}
+	if strings.Join(rows[2], "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, but got %v", expected, rows[2])
+	}
+	if rows[1][8] != "bug" || rows[1][7] != "high" {
+		t.Errorf("Expected the parent row to carry its label and priority, but got %v", rows[1])
+	}
+}
+
+const trelloExport = `{
+  "name": "Trello board",
+  "labels": [
+    {"id": "l1", "name": "Bug", "color": "red"},
+    {"id": "l2", "name": "", "color": "green_dark"}
+  ],
+  "lists": [
+    {"id": "b", "name": "Doing", "closed": false, "pos": 2},
+    {"id": "a", "name": "Backlog", "closed": false, "pos": 1},
+    {"id": "c", "name": "Old", "closed": true, "pos": 3}
+  ],
+  "cards": [
+    {"id": "c2", "name": "Second", "desc": "", "idList": "a", "closed": false, "pos": 20, "due": null, "idLabels": []},
+    {"id": "c1", "name": "A card with a name that is far too long for this site", "desc": "Details", "idList": "a",
+      "closed": false, "pos": 10, "due": "2023-12-01T12:00:00.000Z", "idLabels": ["l1", "l2"]},
+    {"id": "c3", "name": "Archived", "desc": "", "idList": "b", "closed": true, "pos": 1, "due": null, "idLabels": []}
+  ],
+  "checklists": [
+    {"id": "k1", "idCard": "c1", "pos": 1, "checkItems": [
+      {"name": "Second item", "state": "incomplete", "pos": 2},
+      {"name": "First item", "state": "complete", "pos": 1}
+    ]}
+  ]
+}`
+
+func TestParseTrello(t *testing.T) {
+	file, err := boardfile.ParseTrello(strings.NewReader(trelloExport))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if len(file.Columns) != 2 || file.Columns[0].Name != "Backlog" || len(file.Columns[1].Cards) != 0 {
+		t.Fatalf("Expected open lists in order without archived cards, but got %+v", file.Columns)
+	}
+	if len(file.Labels) != 2 || file.Labels[0].Color != "#eb5a46" || file.Labels[1].Name != "green_dark" {
+		t.Errorf("Expected mapped labels, but got %+v %+v", file.Labels[0], file.Labels[1])
+	}
+
+	card := file.Columns[0].Cards[0]
+	if len([]rune(card.Name)) > types.MaxCardNameLength || !strings.HasPrefix(card.Content, "A card with a name that is far too long") {
+		t.Errorf("Expected a shortened name with the full name in the content, but got %q and %q", card.Name, card.Content)
+	}
+	if card.DueOn != "2023-12-01" || len(card.Labels) != 2 {
+		t.Errorf("Expected due date and two labels, but got %q and %v", card.DueOn, card.Labels)
+	}
+	if len(card.Cards) != 2 || card.Cards[0].Name != "First item" || !card.Cards[0].Done {
+		t.Errorf("Expected checklist items as ordered sub-cards, but got %+v", card.Cards)
+	}
+
+	if _, _, err := file.Board(); err != nil {
+		t.Errorf("Expected the Trello board to be valid, but got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/chat"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHubPassesSenderToOnMessage(t *testing.T) {
+	senders := make(chan chat.Member, 1)
+	hub := chat.NewHub(func(sender chat.Member, message []byte) { senders <- sender })
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chat.ServeWs(hub, w, r, chat.Member{ID: 1, Name: "Ada Lovelace"})
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Expected to join the chat, but got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	message := `{"nickname": "Mallory", "chat_message": "hi @bob.smith"}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatalf("Expected no error sending the message, but got %v", err)
+	}
+
+	select {
+	case sender := <-senders:
+		if sender.ID != 1 || sender.Name != "Ada Lovelace" {
+			t.Errorf("Expected the logged in sender, not the nickname, but got %+v", sender)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected onMessage to be called, but it was not")
+	}
+}
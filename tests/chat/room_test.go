@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/chat"
+
+	"github.com/gorilla/websocket"
+)
+
+// presenceNames renders a room's presence as its members' names.
+func presenceNames(room string, members []chat.Member) []byte {
+	names := []string{}
+	for _, member := range members {
+		names = append(names, member.Name)
+	}
+	return []byte("presence:" + strings.Join(names, ","))
+}
+
+// startRoomServer serves hub's rooms to members described by the id, name
+// and role query parameters. The room query parameter defaults to "test".
+func startRoomServer(t *testing.T, hub *chat.RoomHub) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		member := chat.Member{ID: id, Name: r.URL.Query().Get("name"), Role: r.URL.Query().Get("role")}
+		room := r.URL.Query().Get("room")
+		if room == "" {
+			room = "test"
+		}
+		chat.ServeRoom(hub, w, r, room, member)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func joinRoom(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/?"+query, nil)
+	if err != nil {
+		t.Fatalf("Expected to join the room, but got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) string {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected a message, but got %v", err)
+	}
+	return string(message)
+}
+
+func TestRoomHubPublishByRole(t *testing.T) {
+	hub := chat.NewRoomHub(presenceNames)
+	go hub.Run()
+	server := startRoomServer(t, hub)
+
+	viewer := joinRoom(t, server, "id=1&name=Ada&role=viewer")
+	readMessage(t, viewer)
+	editor := joinRoom(t, server, "id=2&name=Bob&role=editor")
+	readMessage(t, viewer)
+	readMessage(t, editor)
+
+	hub.PublishByRole("test", map[string][]byte{"viewer": []byte("read"), "editor": []byte("write")})
+
+	if message := readMessage(t, viewer); message != "read" {
+		t.Errorf("Expected the viewer to get %q, but got %q", "read", message)
+	}
+	if message := readMessage(t, editor); message != "write" {
+		t.Errorf("Expected the editor to get %q, but got %q", "write", message)
+	}
+}
+
+func TestRoomHubPresence(t *testing.T) {
+	hub := chat.NewRoomHub(presenceNames)
+	go hub.Run()
+	server := startRoomServer(t, hub)
+
+	ada := joinRoom(t, server, "id=1&name=Ada")
+	if message := readMessage(t, ada); message != "presence:Ada" {
+		t.Errorf("Expected %q, but got %q", "presence:Ada", message)
+	}
+
+	bob := joinRoom(t, server, "id=2&name=Bob")
+	for _, conn := range []*websocket.Conn{ada, bob} {
+		if message := readMessage(t, conn); message != "presence:Ada,Bob" {
+			t.Errorf("Expected %q, but got %q", "presence:Ada,Bob", message)
+		}
+	}
+
+	adaAgain := joinRoom(t, server, "id=1&name=Ada")
+	for _, conn := range []*websocket.Conn{ada, bob, adaAgain} {
+		if message := readMessage(t, conn); message != "presence:Ada,Bob" {
+			t.Errorf("Expected a second tab to list Ada once, but got %q", message)
+		}
+	}
+
+	bob.Close()
+	for _, conn := range []*websocket.Conn{ada, adaAgain} {
+		if message := readMessage(t, conn); message != "presence:Ada" {
+			t.Errorf("Expected %q after Bob left, but got %q", "presence:Ada", message)
+		}
+	}
+}
+
+func TestRoomHubPublish(t *testing.T) {
+	hub := chat.NewRoomHub(presenceNames)
+	go hub.Run()
+	server := startRoomServer(t, hub)
+
+	first := joinRoom(t, server, "id=1&name=Ada&room=first")
+	readMessage(t, first)
+	second := joinRoom(t, server, "id=2&name=Bob&room=second")
+	readMessage(t, second)
+
+	hub.Publish("first", []byte("one"))
+	hub.Publish("second", []byte("two"))
+
+	if message := readMessage(t, first); message != "one" {
+		t.Errorf("Expected %q, but got %q", "one", message)
+	}
+	if message := readMessage(t, second); message != "two" {
+		t.Errorf("Expected only the second room's message %q, but got %q", "two", message)
+	}
+}
+
+func TestMemberInitials(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Ada Lovelace", "AL"},
+		{"ada byron lovelace", "AB"},
+		{"Ada", "A"},
+		{"  ", ""},
+	}
+
+	for _, test := range tests {
+		if initials := (chat.Member{Name: test.name}).Initials(); initials != test.expected {
+			t.Errorf("Expected initials %q for %q, but got %q", test.expected, test.name, initials)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"testing"
+
+	"go_api/types"
+)
+
+func TestCardChangesAreRecordedInActivity(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+	board := createTestBoard(t, store, user)
+	if len(board.Columns) < 2 {
+		t.Fatalf("Expected the test board to have at least 2 columns, but got %d", len(board.Columns))
+	}
+	from, to := board.Columns[0], board.Columns[1]
+
+	card, err := types.NewCard(from.ID, "Write tests", "", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := store.CreateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error creating the card, but got %v", err)
+	}
+
+	card.Name = "Write more tests"
+	card.AssigneeID = &user.ID
+	if err := store.UpdateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error updating the card, but got %v", err)
+	}
+	// Saving the card unchanged records nothing.
+	if err := store.UpdateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error updating the card, but got %v", err)
+	}
+
+	current, err := store.GetBoard(board.ID)
+	if err != nil {
+		t.Fatalf("Expected no error reading the board, but got %v", err)
+	}
+	move := &types.MoveCardRequest{CardID: card.ID, ColumnID: to.ID, Version: current.Version}
+	if err := store.MoveCard(board.ID, move, nil); err != nil {
+		t.Fatalf("Expected no error moving the card, but got %v", err)
+	}
+
+	comment, err := types.NewCardComment(card.ID, user.ID, "Done soon")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := store.CreateCardComment(comment); err != nil {
+		t.Fatalf("Expected no error creating the comment, but got %v", err)
+	}
+
+	events, err := store.GetCardEvents(card.ID)
+	if err != nil {
+		t.Fatalf("Expected no error reading the activity, but got %v", err)
+	}
+
+	expected := []types.CardEvent{
+		{Kind: types.EventCreated, Author: "Ada Lovelace"},
+		{Kind: types.EventRenamed, Author: "Ada Lovelace", From: "Write tests", To: "Write more tests"},
+		{Kind: types.EventAssigned, Author: "Ada Lovelace", To: "Ada Lovelace"},
+		{Kind: types.EventMoved, From: from.Name, To: to.Name},
+		{Kind: types.EventComment, Author: "Ada Lovelace", Body: "Done soon"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, but got %d", len(expected), len(events))
+	}
+	for i, want := range expected {
+		got := events[i]
+		if got.Kind != want.Kind || got.Author != want.Author || got.From != want.From || got.To != want.To || got.Body != want.Body {
+			t.Errorf("Expected event %d to be %+v, but got %+v", i, want, *got)
+		}
+		if got.CardID != card.ID {
+			t.Errorf("Expected event %d to be on card %d, but got %d", i, card.ID, got.CardID)
+		}
+	}
+	if events[3].UserID != nil {
+		t.Errorf("Expected the move without an actor to have no user, but got %v", *events[3].UserID)
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_api/database"
+	"go_api/types"
+)
+
+func testBlobKey(t *testing.T, store *database.DbConnection) string {
+	key := "attachments/test-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	t.Cleanup(func() { store.DB.Exec(`DELETE FROM blobs WHERE key = $1`, key) })
+	return key
+}
+
+func TestCreateBlobStoresOnlyNewKeys(t *testing.T) {
+	store := openStore(t)
+	key := testBlobKey(t, store)
+
+	failed := errors.New("disk full")
+	if err := store.CreateBlob(types.NewBlob(key, "", 4, "text/plain"), func() error { return failed }); err != failed {
+		t.Fatalf("Expected the error of put, but got %v", err)
+	}
+
+	puts := 0
+	put := func() error {
+		puts++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.CreateBlob(types.NewBlob(key, "", 4, "text/plain"), put); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+
+	if puts != 1 {
+		t.Errorf("Expected the data to be stored once after the failed attempt, but got %d", puts)
+	}
+}
+
+func TestCreateBlobWaitsForTheCollector(t *testing.T) {
+	store := openStore(t)
+	key := testBlobKey(t, store)
+
+	removing := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	events := []string{}
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// The file was never registered, so the collector may delete it.
+		_, err := store.DeleteUnreferencedBlob(key, time.Now().UTC(), func() error {
+			close(removing)
+			<-release
+			record("removed")
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Expected no error collecting the blob, but got %v", err)
+		}
+	}()
+	<-removing
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := store.CreateBlob(types.NewBlob(key, "", 4, "text/plain"), func() error {
+			record("stored")
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Expected no error creating the blob, but got %v", err)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if len(events) != 2 || events[0] != "removed" || events[1] != "stored" {
+		t.Errorf("Expected the file to be stored again after the collector removed it, but got %v", events)
+	}
+}
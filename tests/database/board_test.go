@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"go_api/database"
+	"go_api/types"
+)
+
+func TestMoveCardRefusesSubCardNeighbours(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+	board := createTestBoard(t, store, user)
+	columnID := board.Columns[0].ID
+
+	createCard := func(name string, parent *int) *types.Card {
+		card, err := types.NewCard(columnID, name, "", parent)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if err := store.CreateCard(card, &user.ID); err != nil {
+			t.Fatalf("Expected no error creating the card, but got %v", err)
+		}
+		return card
+	}
+	first := createCard("First", nil)
+	item := createCard("Checklist item", &first.ID)
+	second := createCard("Second", nil)
+
+	current, err := store.GetBoard(board.ID)
+	if err != nil {
+		t.Fatalf("Expected no error reading the board, but got %v", err)
+	}
+
+	move := &types.MoveCardRequest{CardID: second.ID, ColumnID: columnID, AfterID: &item.ID, Version: current.Version}
+	if err := store.MoveCard(board.ID, move, &user.ID); !errors.Is(err, database.ErrBoardChanged) {
+		t.Errorf("Expected %v when dropping next to a sub-card, but got %v", database.ErrBoardChanged, err)
+	}
+
+	move = &types.MoveCardRequest{CardID: second.ID, ColumnID: columnID, BeforeID: &first.ID, Version: current.Version}
+	if err := store.MoveCard(board.ID, move, &user.ID); err != nil {
+		t.Errorf("Expected no error dropping before a top-level card, but got %v", err)
+	}
+}
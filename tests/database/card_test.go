@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go_api/types"
+)
+
+func TestUpdateCardReadsBackAssigneeDueDateAndPriority(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+	board := createTestBoard(t, store, user)
+
+	card, err := types.NewCard(board.Columns[0].ID, "Write tests", "", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := store.CreateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error creating the card, but got %v", err)
+	}
+
+	dueOn := time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC)
+	card.AssigneeID = &user.ID
+	card.DueOn = &dueOn
+	card.Priority = types.PriorityHigh
+	if err := store.UpdateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error updating the card, but got %v", err)
+	}
+
+	saved, err := store.GetCard(card.ID)
+	if err != nil {
+		t.Fatalf("Expected no error reading the card back, but got %v", err)
+	}
+
+	if saved.AssigneeID == nil || *saved.AssigneeID != user.ID {
+		t.Errorf("Expected assignee %d, but got %v", user.ID, saved.AssigneeID)
+	}
+	if saved.Assignee != "Ada Lovelace" {
+		t.Errorf("Expected assignee name %q, but got %q", "Ada Lovelace", saved.Assignee)
+	}
+	if saved.DueOn == nil || saved.DueOn.Format("2006-01-02") != "2023-12-01" {
+		t.Errorf("Expected due date 2023-12-01, but got %v", saved.DueOn)
+	}
+	if saved.Priority != types.PriorityHigh {
+		t.Errorf("Expected priority %q, but got %q", types.PriorityHigh, saved.Priority)
+	}
+	if !saved.IsOverdue(dueOn.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the card to be overdue the day after its due date")
+	}
+}
+
+func TestCardChangesBumpBoardVersion(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+	board := createTestBoard(t, store, user)
+
+	card, err := types.NewCard(board.Columns[0].ID, "Write tests", "", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := store.CreateCard(card, &user.ID); err != nil {
+		t.Fatalf("Expected no error creating the card, but got %v", err)
+	}
+
+	version := func() int {
+		current, err := store.GetBoard(board.ID)
+		if err != nil {
+			t.Fatalf("Expected no error reading the board, but got %v", err)
+		}
+		return current.Version
+	}
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"updating the card", func() error {
+			card.Name = "Write more tests"
+			return store.UpdateCard(card, &user.ID)
+		}},
+		{"marking the card done", func() error { return store.ToggleCardDone(card.ID) }},
+		{"deleting the card", func() error { return store.DeleteCard(card.ID, false) }},
+	}
+
+	for _, test := range changes {
+		before := version()
+		if err := test.change(); err != nil {
+			t.Fatalf("Expected no error %s, but got %v", test.name, err)
+		}
+		if after := version(); after != before+1 {
+			t.Errorf("Expected %s to bump the board version from %d to %d, but got %d", test.name, before, before+1, after)
+		}
+	}
+}
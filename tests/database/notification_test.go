@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go_api/types"
+)
+
+func TestGetUsersByHandles(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+
+	users, err := store.GetUsersByHandles([]string{"ada.lovelace", "nobody.here"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	found := false
+	for _, u := range users {
+		if u.Handle() != "ada.lovelace" {
+			t.Errorf("Expected only users with a mentioned handle, but got %q", u.Handle())
+		}
+		found = found || u.ID == user.ID
+	}
+	if !found {
+		t.Errorf("Expected user %d among the mentioned users, but got %d users", user.ID, len(users))
+	}
+}
+
+func TestCountMentionsSince(t *testing.T) {
+	store := openStore(t)
+	actor := createTestUser(t, store)
+	mentioned := createTestUser(t, store)
+	before := time.Now().UTC().Add(-time.Second)
+
+	notification := types.NewNotification(mentioned.ID, types.NotifyMention, "Ada mentioned you in the chat", "/chat")
+	notification.ActorID = &actor.ID
+	if err := store.CreateNotification(notification); err != nil {
+		t.Fatalf("Expected no error creating the notification, but got %v", err)
+	}
+
+	if count, err := store.CountMentionsSince(actor.ID, before); err != nil || count != 1 {
+		t.Errorf("Expected 1 mention by the actor, but got %d, %v", count, err)
+	}
+	if count, err := store.CountMentionsSince(mentioned.ID, before); err != nil || count != 0 {
+		t.Errorf("Expected no mentions by the mentioned user, but got %d, %v", count, err)
+	}
+	if count, err := store.CountMentionsSince(actor.ID, time.Now().UTC().Add(time.Second)); err != nil || count != 0 {
+		t.Errorf("Expected no mentions after the window, but got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/pagination"
+	"go_api/types"
+)
+
+func TestSearchPostsRanksTitlesFirstAndMarksSnippets(t *testing.T) {
+	store := openStore(t)
+	user := createTestUser(t, store)
+	// A word no other post in the database contains.
+	word := "zq" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	createPost := func(name, content string, status types.PostStatus) *types.Post {
+		post, err := types.NewPost(name, content, "<p>"+content+"</p>", &user.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if err := post.SetStatus(status, nil, time.Now().UTC()); err != nil {
+			t.Fatalf("Expected no error setting the status, but got %v", err)
+		}
+		if err := store.CreatePost(post); err != nil {
+			t.Fatalf("Expected no error creating the post, but got %v", err)
+		}
+		t.Cleanup(func() { store.DeletePost(post.ID) })
+		return post
+	}
+	body := createPost("Release notes", "Notes about the "+word+" migration.", types.PostPublished)
+	title := createPost("All about "+word, "Nothing else to say here.", types.PostPublished)
+	createPost("Draft on "+word, "Not public yet.", types.PostDraft)
+
+	results, more, err := store.SearchPosts(word, pagination.Params{Size: 10, Page: 1})
+	if err != nil {
+		t.Fatalf("Expected no error searching, but got %v", err)
+	}
+	if more {
+		t.Errorf("Expected a single page of results")
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected the 2 published posts, but got %d results", len(results))
+	}
+	if results[0].ID != title.ID || results[1].ID != body.ID {
+		t.Errorf("Expected the title match %d before the body match %d, but got %d and %d", title.ID, body.ID, results[0].ID, results[1].ID)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("Expected the title match to rank higher, but got %v and %v", results[0].Rank, results[1].Rank)
+	}
+	if results[1].URL != "/posts/"+body.Slug {
+		t.Errorf("Expected URL %q, but got %q", "/posts/"+body.Slug, results[1].URL)
+	}
+
+	marked := types.SearchMatchStart + word + types.SearchMatchStop
+	if !strings.Contains(results[1].Snippet, marked) {
+		t.Errorf("Expected the snippet to mark %q, but got %q", word, results[1].Snippet)
+	}
+
+	results, more, err = store.SearchPosts(word, pagination.Params{Size: 1, Page: 1})
+	if err != nil {
+		t.Fatalf("Expected no error searching, but got %v", err)
+	}
+	if len(results) != 1 || !more {
+		t.Errorf("Expected one result and more to follow, but got %d results and more %v", len(results), more)
+	}
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"go_api/database"
+	"go_api/types"
+)
+
+// openStore connects to the database named by TEST_DATABASE_URL, migrating
+// it first. The tests write to it, so it should not hold real data; they are
+// skipped when the variable is not set.
+func openStore(t *testing.T) *database.DbConnection {
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	store, err := database.NewDbConnection(connString)
+	if err != nil {
+		t.Fatalf("Expected to connect to the test database, but got %v", err)
+	}
+	t.Cleanup(func() { store.DB.Close() })
+	return store
+}
+
+// createTestUser stores a user with an email no other run has used.
+func createTestUser(t *testing.T, store *database.DbConnection) *types.User {
+	email := "test-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "@example.com"
+	user, err := types.NewUser("Ada", "Lovelace", email, "password")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := store.CreateUser(user); err != nil {
+		t.Fatalf("Expected no error creating the user, but got %v", err)
+	}
+
+	user, err = store.GetUserByEmail(email)
+	if err != nil {
+		t.Fatalf("Expected no error reading the user back, but got %v", err)
+	}
+	t.Cleanup(func() { store.DB.Exec(`DELETE FROM users WHERE id = $1`, user.ID) })
+	return user
+}
+
+// createTestBoard stores a board with the default columns owned by owner.
+func createTestBoard(t *testing.T, store *database.DbConnection, owner *types.User) *types.Board {
+	board, err := types.NewBoard("Test board")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	board.OwnerID = &owner.ID
+	if err := store.CreateBoard(board); err != nil {
+		t.Fatalf("Expected no error creating the board, but got %v", err)
+	}
+	t.Cleanup(func() { store.DB.Exec(`DELETE FROM boards WHERE id = $1`, board.ID) })
+	return board
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go_api/diff"
+)
+
+func render(lines []diff.Line) string {
+	out := ""
+	for _, line := range lines {
+		switch line.Op {
+		case diff.Insert:
+			out += "+" + line.Text + "\n"
+		case diff.Delete:
+			out += "-" + line.Text + "\n"
+		default:
+			out += " " + line.Text + "\n"
+		}
+	}
+	return out
+}
+
+func TestLinesIdentical(t *testing.T) {
+	lines := diff.Lines("a\nb\n", "a\nb")
+
+	if diff.Changed(lines) {
+		t.Errorf("Expected no changes, but got %q", render(lines))
+	}
+}
+
+func TestLinesEdit(t *testing.T) {
+	lines := diff.Lines("one\ntwo\nthree\nfour", "one\n2\nthree\nfour\nfive")
+
+	expected := " one\n-two\n+2\n three\n four\n+five\n"
+	if got := render(lines); got != expected {
+		t.Errorf("Expected diff %q, but got %q", expected, got)
+	}
+
+	if lines[2].NewNumber != 2 || lines[2].OldNumber != 0 {
+		t.Errorf("Expected inserted line to be new line 2, but got old %d new %d", lines[2].OldNumber, lines[2].NewNumber)
+	}
+	if lines[5].NewNumber != 5 {
+		t.Errorf("Expected appended line to be new line 5, but got %d", lines[5].NewNumber)
+	}
+}
+
+func TestLinesFromEmpty(t *testing.T) {
+	lines := diff.Lines("", "a\nb")
+
+	expected := "+a\n+b\n"
+	if got := render(lines); got != expected {
+		t.Errorf("Expected diff %q, but got %q", expected, got)
+	}
+}
+
+func TestLinesCRLF(t *testing.T) {
+	lines := diff.Lines("a\r\nb\r\n", "a\nb\n")
+
+	if diff.Changed(lines) {
+		t.Errorf("Expected line endings to be ignored, but got %q", render(lines))
+	}
+}
+
+func TestLinesReplacesLargeBlocksWhole(t *testing.T) {
+	var oldText, newText strings.Builder
+	oldText.WriteString("title\n")
+	newText.WriteString("title\n")
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&oldText, "old %d\n", i)
+		fmt.Fprintf(&newText, "new %d\n", i)
+	}
+	newText.WriteString("footer\n")
+
+	lines := diff.Lines(oldText.String(), newText.String())
+
+	if len(lines) != 6002 {
+		t.Fatalf("Expected 6002 lines, but got %d", len(lines))
+	}
+	if lines[1].Op != diff.Delete || lines[1].OldNumber != 2 {
+		t.Errorf("Expected old line 2 to be deleted first, but got %+v", lines[1])
+	}
+	if lines[3001].Op != diff.Insert || lines[3001].NewNumber != 2 {
+		t.Errorf("Expected new line 2 to be inserted after the deletions, but got %+v", lines[3001])
+	}
+	if last := lines[6001]; last.Op != diff.Insert || last.Text != "footer" || last.NewNumber != 3002 {
+		t.Errorf("Expected the footer to be inserted as new line 3002, but got %+v", last)
+	}
+}
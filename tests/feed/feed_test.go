@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/feed"
+)
+
+func sampleFeed() *feed.Feed {
+	published := time.Date(2023, 11, 10, 9, 0, 0, 0, time.UTC)
+	updated := time.Date(2023, 11, 12, 18, 30, 0, 0, time.UTC)
+
+	return &feed.Feed{
+		Title:   "Blog",
+		URL:     "https://example.com/posts",
+		FeedURL: "https://example.com/posts/feed.atom",
+		Updated: updated,
+		Entries: []*feed.Entry{{
+			ID:         "https://example.com/posts/1",
+			Title:      "Go & generics",
+			URL:        "https://example.com/posts/go-generics",
+			Author:     "John Doe",
+			Content:    "<p>Hello <code>T</code></p>",
+			Categories: []string{"go"},
+			Published:  published,
+			Updated:    updated,
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	out, err := sampleFeed().Atom()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	var parsed struct {
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("Expected valid XML, but got %v", err)
+	}
+
+	if parsed.Updated != "2023-11-12T18:30:00Z" {
+		t.Errorf("Expected feed updated 2023-11-12T18:30:00Z, but got %s", parsed.Updated)
+	}
+
+	if len(parsed.Entries) != 1 || parsed.Entries[0].Title != "Go & generics" {
+		t.Fatalf("Expected one entry titled Go & generics, but got %+v", parsed.Entries)
+	}
+
+	if parsed.Entries[0].Content != "<p>Hello <code>T</code></p>" {
+		t.Errorf("Expected content to round-trip, but got %s", parsed.Entries[0].Content)
+	}
+
+	if !strings.Contains(string(out), `xmlns="http://www.w3.org/2005/Atom"`) {
+		t.Error("Expected the Atom namespace on the feed element")
+	}
+}
+
+func TestRSS(t *testing.T) {
+	out, err := sampleFeed().RSS()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	var parsed struct {
+		Channel struct {
+			Items []struct {
+				PubDate string `xml:"pubDate"`
+				GUID    string `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("Expected valid XML, but got %v", err)
+	}
+
+	if len(parsed.Channel.Items) != 1 {
+		t.Fatalf("Expected 1 item, but got %d", len(parsed.Channel.Items))
+	}
+
+	if parsed.Channel.Items[0].PubDate != "Fri, 10 Nov 2023 09:00:00 +0000" {
+		t.Errorf("Expected RFC 1123 pubDate, but got %s", parsed.Channel.Items[0].PubDate)
+	}
+
+	for _, ns := range []string{`xmlns:atom="http://www.w3.org/2005/Atom"`, `xmlns:dc="http://purl.org/dc/elements/1.1/"`, "<atom:link", "<dc:creator>John Doe</dc:creator>"} {
+		if !strings.Contains(string(out), ns) {
+			t.Errorf("Expected RSS output to contain %s", ns)
+		}
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	out, err := feed.Sitemap([]feed.SitemapURL{
+		{Loc: "https://example.com/"},
+		{Loc: "https://example.com/posts/a", LastModified: time.Date(2023, 11, 12, 0, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	doc := string(out)
+	if strings.Count(doc, "<url>") != 2 {
+		t.Errorf("Expected 2 urls, but got %s", doc)
+	}
+
+	if strings.Count(doc, "<lastmod>") != 1 || !strings.Contains(doc, "<lastmod>2023-11-12T00:00:00Z</lastmod>") {
+		t.Errorf("Expected a single lastmod, but got %s", doc)
+	}
+}
+
+func TestETag(t *testing.T) {
+	a := feed.ETag([]byte("a"))
+
+	if a != feed.ETag([]byte("a")) {
+		t.Error("Expected the same body to give the same ETag")
+	}
+
+	if a == feed.ETag([]byte("b")) {
+		t.Error("Expected different bodies to give different ETags")
+	}
+
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) {
+		t.Errorf("Expected a quoted ETag, but got %s", a)
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+// serveActivity serves the routes against a store with card 3, which was
+// renamed by a user since deleted and then commented on.
+func serveActivity(t *testing.T) (http.Handler, *fakeStore) {
+	t.Setenv("JWT_SECRET", "test secret")
+
+	store := newFakeStore()
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "New name"}
+	renamed := types.NewCardEvent(3, nil, types.EventRenamed, "Old name", "New name")
+	renamed.ID = 1
+	comment, _ := types.NewCardComment(3, store.user.ID, "<b>Looks good</b>")
+	comment.ID = 2
+	comment.Author = "Ada Lovelace"
+	store.cardEvents[3] = []*types.CardEvent{renamed, comment}
+
+	return handlers.NewAPIServer(":0", store, nil, nil).Routes(), store
+}
+
+func serveActivityRequest(t *testing.T, routes http.Handler, store *fakeStore, method, body string) *httptest.ResponseRecorder {
+	target := "/workspace/3"
+	if method == http.MethodPost {
+		target += "/comments"
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("HX-Request", "true")
+	logIn(t, req, store)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGetCardListsActivity(t *testing.T) {
+	routes, store := serveActivity(t)
+
+	rr := serveActivityRequest(t, routes, store, http.MethodGet, "")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"<strong>Someone</strong>",
+		"renamed this card from <em>Old name</em> to <em>New name</em>",
+		"<strong>Ada Lovelace</strong>",
+		`<div class="comment-body">&lt;b&gt;Looks good&lt;/b&gt;</div>`,
+		`hx-post="/workspace/3/comments"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the activity to contain %q, but got %s", want, body)
+		}
+	}
+	if strings.Index(body, "renamed this card") > strings.Index(body, "Looks good") {
+		t.Errorf("Expected events in the order they happened, but got %s", body)
+	}
+
+	store.role = types.MemberViewer
+	rr = serveActivityRequest(t, routes, store, http.MethodGet, "")
+	if !strings.Contains(rr.Body.String(), "Looks good") {
+		t.Errorf("Expected a viewer to see the activity, but got %s", rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), `hx-post="/workspace/3/comments"`) {
+		t.Errorf("Expected no comment form for a viewer, but got %s", rr.Body)
+	}
+}
+
+func TestCreateCardComment(t *testing.T) {
+	routes, store := serveActivity(t)
+
+	rr := serveActivityRequest(t, routes, store, http.MethodPost, `{"body": "  Ship it  "}`)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	events := store.cardEvents[3]
+	if len(events) != 3 {
+		t.Fatalf("Expected the comment to be recorded, but got %d events", len(events))
+	}
+	comment := events[2]
+	if comment.Kind != types.EventComment || comment.Body != "Ship it" {
+		t.Errorf("Expected a comment %q, but got a %s %q", "Ship it", comment.Kind, comment.Body)
+	}
+	if comment.UserID == nil || *comment.UserID != store.user.ID {
+		t.Errorf("Expected the comment to be by user %d, but got %v", store.user.ID, comment.UserID)
+	}
+	if !strings.Contains(rr.Body.String(), `id="card-event-3"`) || !strings.Contains(rr.Body.String(), "<strong>Ada Lovelace</strong>") {
+		t.Errorf("Expected the new entry by Ada Lovelace, but got %s", rr.Body)
+	}
+}
+
+func TestCreateCardCommentRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		role types.MemberRole
+		body string
+	}{
+		{"an empty comment", types.MemberEditor, `{"body": "   "}`},
+		{"a viewer's comment", types.MemberViewer, `{"body": "Ship it"}`},
+	}
+
+	for _, test := range tests {
+		routes, store := serveActivity(t)
+		store.role = test.role
+
+		serveActivityRequest(t, routes, store, http.MethodPost, test.body)
+
+		if events := store.cardEvents[3]; len(events) != 2 {
+			t.Errorf("Expected %s not to be recorded, but got %d events", test.name, len(events))
+		}
+	}
+}
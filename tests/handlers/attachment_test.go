@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/storage"
+	"go_api/types"
+)
+
+// serveAttachments serves the routes against a store with cards 3 and 4 and
+// one attachment on each.
+func serveAttachments(t *testing.T) (http.Handler, *fakeStore) {
+	t.Setenv("JWT_SECRET", "test secret")
+
+	store := newFakeStore()
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "Mine"}
+	store.cards[4] = &types.Card{ID: 4, BoardID: 2, Name: "Other"}
+	for id, cardID := range map[int]int{1: 3, 2: 4} {
+		attachment := types.NewAttachment(types.AttachmentCard, cardID, "attachments/key", "notes.txt", "text/plain", 5)
+		attachment.ID = id
+		store.attachments[id] = attachment
+	}
+
+	blobs := storage.NewLocalStore(t.TempDir(), "/uploads")
+	return handlers.NewAPIServer(":0", store, blobs, nil).Routes(), store
+}
+
+func serveAttachmentRequest(t *testing.T, routes http.Handler, store *fakeStore, req *http.Request) *httptest.ResponseRecorder {
+	logIn(t, req, store)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUploadCardAttachmentRejectsType(t *testing.T) {
+	routes, store := serveAttachments(t)
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "page.html")
+	part.Write([]byte("<!DOCTYPE html><html><body>Hi</body></html>"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/workspace/3/attachments", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := serveAttachmentRequest(t, routes, store, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "unsupported file type text/html") {
+		t.Errorf("Expected an unsupported file type error, but got %s", rr.Body)
+	}
+}
+
+func TestDeleteCardAttachment(t *testing.T) {
+	routes, store := serveAttachments(t)
+
+	req := httptest.NewRequest(http.MethodDelete, "/workspace/3/attachments/1", nil)
+	rr := serveAttachmentRequest(t, routes, store, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if _, ok := store.attachments[1]; ok {
+		t.Errorf("Expected attachment 1 to be deleted, but it is still stored")
+	}
+	if strings.Contains(rr.Body.String(), "notes.txt") {
+		t.Errorf("Expected the deleted attachment to be gone from the list, but got %s", rr.Body)
+	}
+}
+
+func TestDeleteCardAttachmentOfAnotherCard(t *testing.T) {
+	routes, store := serveAttachments(t)
+
+	req := httptest.NewRequest(http.MethodDelete, "/workspace/3/attachments/2", nil)
+	rr := serveAttachmentRequest(t, routes, store, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, rr.Code)
+	}
+	if _, ok := store.attachments[2]; !ok {
+		t.Errorf("Expected the other card's attachment to be kept, but it was deleted")
+	}
+}
+
+func TestGetCardAttachmentsAsViewer(t *testing.T) {
+	routes, store := serveAttachments(t)
+	store.role = types.MemberViewer
+
+	req := httptest.NewRequest(http.MethodGet, "/workspace/3/attachments", nil)
+	rr := serveAttachmentRequest(t, routes, store, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if !strings.Contains(rr.Body.String(), `href="/uploads/attachments/key"`) {
+		t.Errorf("Expected a link to the attachment, but got %s", rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), "hx-post") || strings.Contains(rr.Body.String(), "hx-delete") {
+		t.Errorf("Expected no upload or delete controls for a viewer, but got %s", rr.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/workspace/3/attachments/1", nil)
+	serveAttachmentRequest(t, routes, store, req)
+	if _, ok := store.attachments[1]; !ok {
+		t.Errorf("Expected a viewer not to delete attachments, but attachment 1 was deleted")
+	}
+}
+
+func TestDeletePostAttachmentBySlug(t *testing.T) {
+	routes, store := serveAttachments(t)
+	store.user.Role = types.Role{Name: types.RoleAuthor}
+	store.posts = []*types.Post{{ID: 5, Name: "Hello world", Slug: "hello-world", UserID: &store.user.ID}}
+	attachment := types.NewAttachment(types.AttachmentPost, 5, "attachments/key", "notes.txt", "text/plain", 5)
+	attachment.ID = 3
+	store.attachments[3] = attachment
+
+	req := httptest.NewRequest(http.MethodDelete, "/posts/hello-world/attachments/3", nil)
+	rr := serveAttachmentRequest(t, routes, store, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if _, ok := store.attachments[3]; ok {
+		t.Errorf("Expected attachment 3 to be deleted, but it is still stored")
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func serveCardRequest(t *testing.T, store *fakeStore, method string, target string, body string) *httptest.ResponseRecorder {
+	t.Setenv("JWT_SECRET", "test secret")
+	routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	logIn(t, req, store)
+
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestEditCardRejectsEmptyName(t *testing.T) {
+	store := newFakeStore()
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "Keep me", Priority: types.PriorityNone}
+
+	rr := serveCardRequest(t, store, http.MethodPut, "/workspace/3", `{"name": " ", "content": "new"}`)
+
+	if !strings.Contains(rr.Body.String(), "Card title cannot be empty.") {
+		t.Errorf("Expected the form to show the validation error, but got %s", rr.Body)
+	}
+	if !strings.Contains(rr.Body.String(), `hx-put="/workspace/3"`) {
+		t.Errorf("Expected the card form to be rendered again, but got %s", rr.Body)
+	}
+}
+
+// TestCardRoutesIgnoreGetMutations checks the old GET edit and delete links
+// are gone; the fake store panics if anything tries to delete or update.
+func TestCardRoutesIgnoreGetMutations(t *testing.T) {
+	store := newFakeStore()
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "Keep me", Priority: types.PriorityNone}
+
+	for _, target := range []string{"/workspace/3/delete", "/workspace/3/edit"} {
+		serveCardRequest(t, store, http.MethodGet, target, "")
+		if card, err := store.GetCard(3); err != nil || card.Name != "Keep me" {
+			t.Errorf("Expected GET %s to leave the card alone, but got %v, %v", target, card, err)
+		}
+	}
+}
+
+func TestViewerCannotEditCard(t *testing.T) {
+	store := newFakeStore()
+	store.role = types.MemberViewer
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "Keep me", Priority: types.PriorityNone}
+
+	rr := serveCardRequest(t, store, http.MethodPut, "/workspace/3", `{"name": " "}`)
+
+	if strings.Contains(rr.Body.String(), "Card title cannot be empty.") {
+		t.Errorf("Expected a viewer not to reach the card form, but got %s", rr.Body)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/handlers"
+	"go_api/mail"
+	"go_api/types"
+)
+
+// commentNotification posts a comment on another user's post with a forged
+// Host and returns the email sent to the post's author.
+func commentNotification(t *testing.T) mail.Message {
+	t.Setenv("JWT_SECRET", "test secret")
+
+	authorID := 2
+	store := newFakeStore()
+	store.posts = []*types.Post{{ID: 5, Name: "Hello", Slug: "hello", Status: types.PostPublished, UserID: &authorID}}
+	mailer := &fakeMailer{sent: make(chan mail.Message, 1)}
+	routes := handlers.NewAPIServer(":0", store, nil, mailer).Routes()
+
+	req := httptest.NewRequest(http.MethodPost, "/posts/5/comments", strings.NewReader(`{"body": "Nice post"}`))
+	req.Host = "evil.example"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	logIn(t, req, store)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+
+	select {
+	case msg := <-mailer.sent:
+		if len(msg.To) != 1 || msg.To[0] != "user2@example.com" {
+			t.Errorf("Expected the email to go to the post's author, but got %v", msg.To)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the post's author to be emailed, but nothing was sent")
+	}
+	return mail.Message{}
+}
+
+func TestCommentNotificationLinksToSiteURL(t *testing.T) {
+	t.Setenv("SITE_URL", "https://blog.example.com/")
+
+	msg := commentNotification(t)
+
+	if !strings.Contains(msg.Body, "https://blog.example.com/posts/hello#comment-1") {
+		t.Errorf("Expected a link under SITE_URL, but got %q", msg.Body)
+	}
+	if strings.Contains(msg.Body, "evil.example") {
+		t.Errorf("Expected the request's Host to be ignored, but got %q", msg.Body)
+	}
+}
+
+func TestCommentNotificationWithoutSiteURL(t *testing.T) {
+	t.Setenv("SITE_URL", "")
+
+	msg := commentNotification(t)
+
+	if strings.Contains(msg.Body, "evil.example") || strings.Contains(msg.Body, "://") {
+		t.Errorf("Expected no link without SITE_URL, but got %q", msg.Body)
+	}
+	if !strings.Contains(msg.Body, "Nice post") {
+		t.Errorf("Expected the comment in the email, but got %q", msg.Body)
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func TestSitemapListsPublicTaxonomies(t *testing.T) {
+	t.Setenv("SITE_URL", "https://example.com")
+
+	store := newFakeStore()
+	store.tags = []*types.Tag{{ID: 1, Name: "Go", Slug: "go"}}
+	store.categories = []*types.Category{{ID: 2, Name: "Notes", Slug: "notes"}}
+	routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	for _, loc := range []string{"https://example.com/posts/tags/go", "https://example.com/posts/categories/notes"} {
+		if !strings.Contains(rr.Body.String(), "<loc>"+loc+"</loc>") {
+			t.Errorf("Expected the sitemap to list %s, but got %s", loc, rr.Body)
+		}
+	}
+}
+
+func TestRobotsTrustsForwardedProtoOnlyFromProxies(t *testing.T) {
+	t.Setenv("SITE_URL", "")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	routes := handlers.NewAPIServer(":0", newFakeStore(), nil, nil).Routes()
+
+	tests := []struct {
+		remoteAddr string
+		expected   string
+	}{
+		{"203.0.113.7:4321", "Sitemap: http://blog.example/sitemap.xml"},
+		{"10.0.0.2:4321", "Sitemap: https://blog.example/sitemap.xml"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+		req.Host = "blog.example"
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if !strings.Contains(rr.Body.String(), test.expected) {
+			t.Errorf("Expected %q for a request from %s, but got %s", test.expected, test.remoteAddr, rr.Body)
+		}
+	}
+}
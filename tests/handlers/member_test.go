@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func TestBoardShareLinkUsesSiteURL(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test secret")
+	t.Setenv("SITE_URL", "https://app.example.com/")
+
+	store := newFakeStore()
+	store.role = types.MemberAdmin
+	store.boards[2] = &types.Board{ID: 2, Name: "Roadmap", ShareToken: "token123"}
+	routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/workspace/boards/2/members", nil)
+	req.Host = "evil.example"
+	logIn(t, req, store)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), `value="https://app.example.com/shared/boards/token123"`) {
+		t.Errorf("Expected the share link under SITE_URL, but got %s", rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), "evil.example") {
+		t.Errorf("Expected the request's Host to be ignored, but got %s", rr.Body)
+	}
+}
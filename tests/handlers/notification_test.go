@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func TestRemindDueCards(t *testing.T) {
+	now := time.Date(2023, time.November, 30, 9, 0, 0, 0, time.UTC)
+	dueOn := time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC)
+	assigneeID := 7
+	store := &fakeStore{dueCards: []*types.Card{
+		{ID: 3, Name: "Ship it", AssigneeID: &assigneeID, DueOn: &dueOn},
+		{ID: 4, Name: "Unassigned", DueOn: &dueOn},
+	}}
+	server := handlers.NewAPIServer(":0", store, nil, nil)
+
+	server.RemindDueCards(now)
+
+	if len(store.notifications) != 1 {
+		t.Fatalf("Expected 1 notification, but got %d", len(store.notifications))
+	}
+	notification := store.notifications[0]
+	if notification.UserID != assigneeID {
+		t.Errorf("Expected the notification for user %d, but got %d", assigneeID, notification.UserID)
+	}
+	if notification.Kind != types.NotifyDueSoon {
+		t.Errorf("Expected kind %q, but got %q", types.NotifyDueSoon, notification.Kind)
+	}
+	if notification.Message != `"Ship it" is due on Dec 1` {
+		t.Errorf("Expected a due date message, but got %q", notification.Message)
+	}
+	if notification.DedupeKey != "due:3:2023-12-01" {
+		t.Errorf("Expected dedupe key %q, but got %q", "due:3:2023-12-01", notification.DedupeKey)
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func TestDeletePost(t *testing.T) {
+	ownID, otherID := 1, 2
+	tests := []struct {
+		name    string
+		role    string
+		postID  int
+		deleted bool
+	}{
+		{"author deleting their own post", types.RoleAuthor, 1, true},
+		{"author deleting someone else's post", types.RoleAuthor, 2, false},
+		{"admin deleting someone else's post", types.RoleAdmin, 2, true},
+		{"user deleting their own post", types.RoleUser, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("JWT_SECRET", "test secret")
+		store := newFakeStore()
+		store.user.Role = types.Role{Name: test.role}
+		store.posts = []*types.Post{
+			{ID: 1, Name: "Mine", UserID: &ownID},
+			{ID: 2, Name: "Theirs", UserID: &otherID},
+		}
+		routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+
+		req := httptest.NewRequest(http.MethodDelete, "/posts/"+strconv.Itoa(test.postID), nil)
+		logIn(t, req, store)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		_, err := store.GetPost(test.postID)
+		if deleted := err != nil; deleted != test.deleted {
+			t.Errorf("Expected the post to be deleted for %s to be %v, but got %v", test.name, test.deleted, deleted)
+		}
+		if redirect := rr.Header().Get("HX-Redirect"); test.deleted && redirect != "/posts" {
+			t.Errorf("Expected a redirect to /posts for %s, but got %q", test.name, redirect)
+		}
+	}
+}
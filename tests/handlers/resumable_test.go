@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+const testUploadSize = 10
+
+// startUpload serves the routes against a store with card 3 and creates an
+// upload of testUploadSize bytes on it, returning its location.
+func startUpload(t *testing.T) (http.Handler, *fakeStore, string) {
+	inTempDir(t)
+	t.Setenv("JWT_SECRET", "test secret")
+
+	store := newFakeStore()
+	store.cards[3] = &types.Card{ID: 3, BoardID: 2, Name: "Upload here"}
+	routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+
+	checksum := sha256.Sum256([]byte(strings.Repeat("a", testUploadSize)))
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("notes.txt")) +
+		",checksum " + base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(checksum[:])))
+
+	rr := serveUpload(t, routes, store, http.MethodPost, "/workspace/3/uploads", map[string]string{
+		"Upload-Length":   strconv.Itoa(testUploadSize),
+		"Upload-Metadata": metadata,
+	}, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d creating the upload, but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+	}
+	return routes, store, rr.Header().Get("Location")
+}
+
+func serveUpload(t *testing.T, routes http.Handler, store *fakeStore, method string, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	logIn(t, req, store)
+
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+func patchUpload(t *testing.T, routes http.Handler, store *fakeStore, location string, offset int, chunk string) *httptest.ResponseRecorder {
+	return serveUpload(t, routes, store, http.MethodPatch, location, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": strconv.Itoa(offset),
+	}, chunk)
+}
+
+func headOffset(t *testing.T, routes http.Handler, store *fakeStore, location string) string {
+	rr := serveUpload(t, routes, store, http.MethodHead, location, nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d from HEAD, but got %d", http.StatusOK, rr.Code)
+	}
+	if length := rr.Header().Get("Upload-Length"); length != strconv.Itoa(testUploadSize) {
+		t.Errorf("Expected Upload-Length %d, but got %q", testUploadSize, length)
+	}
+	return rr.Header().Get("Upload-Offset")
+}
+
+func TestResumableUploadOffsets(t *testing.T) {
+	routes, store, location := startUpload(t)
+
+	if offset := headOffset(t, routes, store, location); offset != "0" {
+		t.Errorf("Expected a new upload at offset 0, but got %q", offset)
+	}
+
+	rr := patchUpload(t, routes, store, location, 0, "aaaa")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusNoContent, rr.Code, rr.Body)
+	}
+	if offset := rr.Header().Get("Upload-Offset"); offset != "4" {
+		t.Errorf("Expected the PATCH to report offset 4, but got %q", offset)
+	}
+	if offset := headOffset(t, routes, store, location); offset != "4" {
+		t.Errorf("Expected HEAD to report offset 4, but got %q", offset)
+	}
+
+	if rr := patchUpload(t, routes, store, location, 0, "aaaa"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a chunk at the wrong offset, but got %d", http.StatusConflict, rr.Code)
+	}
+
+	// Only the 6 bytes the upload still expects are stored, completing it
+	// with a file whose checksum does not match, which is discarded.
+	rr = patchUpload(t, routes, store, location, 4, "bbbbbbaaaa")
+	if rr.Code != 460 {
+		t.Errorf("Expected status 460 for a checksum mismatch, but got %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serveUpload(t, routes, store, http.MethodHead, location, nil, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the discarded upload to be gone, but got status %d", rr.Code)
+	}
+	if parts, _ := filepath.Glob(filepath.Join("tmp", "uploads", "*.part")); len(parts) != 0 {
+		t.Errorf("Expected no part files left, but got %v", parts)
+	}
+}
+
+func TestCreateUploadOverAttachmentLimit(t *testing.T) {
+	routes, store, _ := startUpload(t)
+
+	size := types.AttachmentRules[types.AttachmentCard].MaxSize + 1
+	rr := serveUpload(t, routes, store, http.MethodPost, "/workspace/3/uploads", map[string]string{
+		"Upload-Length":   strconv.FormatInt(size, 10),
+		"Upload-Metadata": "checksum " + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("0", 64))),
+	}, "")
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, but got %d: %s", http.StatusRequestEntityTooLarge, rr.Code, rr.Body)
+	}
+}
+
+func TestResumableUploadRejectsTypeOnFirstChunk(t *testing.T) {
+	routes, store, location := startUpload(t)
+
+	rr := patchUpload(t, routes, store, location, 0, "<html>")
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, but got %d: %s", http.StatusUnsupportedMediaType, rr.Code, rr.Body)
+	}
+	if !strings.Contains(rr.Body.String(), "unsupported file type text/html") {
+		t.Errorf("Expected an unsupported file type error, but got %s", rr.Body)
+	}
+	if rr := serveUpload(t, routes, store, http.MethodHead, location, nil, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the rejected upload to be gone, but got status %d", rr.Code)
+	}
+}
+
+// slowBody signals started on its first read and then holds the request
+// until release is closed.
+type slowBody struct {
+	started chan struct{}
+	release chan struct{}
+	body    *strings.Reader
+	once    sync.Once
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		close(b.started)
+		<-b.release
+	})
+	return b.body.Read(p)
+}
+
+func TestResumableUploadSerialisesConcurrentPatches(t *testing.T) {
+	routes, store, location := startUpload(t)
+
+	slow := &slowBody{started: make(chan struct{}), release: make(chan struct{}), body: strings.NewReader("aaaa")}
+	first := httptest.NewRequest(http.MethodPatch, location, slow)
+	first.Header.Set("Tus-Resumable", "1.0.0")
+	first.Header.Set("Content-Type", "application/offset+octet-stream")
+	first.Header.Set("Upload-Offset", "0")
+	logIn(t, first, store)
+	firstRR := httptest.NewRecorder()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		routes.ServeHTTP(firstRR, first)
+	}()
+	<-slow.started
+
+	// The second chunk for the same offset arrives while the first one is
+	// still being written.
+	var secondRR *httptest.ResponseRecorder
+	go func() {
+		defer wg.Done()
+		secondRR = patchUpload(t, routes, store, location, 0, "aaaa")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(slow.release)
+	wg.Wait()
+
+	if firstRR.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d for the first PATCH, but got %d", http.StatusNoContent, firstRR.Code)
+	}
+	if secondRR.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for the second PATCH, but got %d", http.StatusConflict, secondRR.Code)
+	}
+	if offset := headOffset(t, routes, store, location); offset != "4" {
+		t.Errorf("Expected offset 4 after the racing PATCHes, but got %q", offset)
+	}
+}
+
+func TestCleanupUploadsRemovesOrphanedPartFiles(t *testing.T) {
+	routes, store, location := startUpload(t)
+	uploadID := strings.TrimPrefix(location, "/workspace/3/uploads/")
+	patchUpload(t, routes, store, location, 0, "aaaa")
+
+	orphan := filepath.Join("tmp", "uploads", "orphan.part")
+	if err := os.WriteFile(orphan, []byte("aaaa"), 0644); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(orphan, old, old); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	handlers.NewAPIServer(":0", store, nil, nil).CleanupUploads(time.Now().UTC())
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("Expected the orphaned part file to be removed, but got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("tmp", "uploads", uploadID+".part")); err != nil {
+		t.Errorf("Expected the part file of the active upload to stay, but got %v", err)
+	}
+	if _, err := store.GetUpload(uploadID); err != nil {
+		t.Errorf("Expected the active upload to stay, but got %v", err)
+	}
+}
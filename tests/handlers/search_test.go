@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func serveSearch(t *testing.T, store *fakeStore, target string) *httptest.ResponseRecorder {
+	routes := handlers.NewAPIServer(":0", store, nil, nil).Routes()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("HX-Request", "true")
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSearchMarksMatchesAndLinksNextPage(t *testing.T) {
+	store := newFakeStore()
+	store.searchResults = []*types.SearchResult{
+		{Kind: types.SearchPost, ID: 1, Title: "Go tips", URL: "/posts/go-tips",
+			Snippet: "Some " + types.SearchMatchStart + "<go>" + types.SearchMatchStop + " tips"},
+		{Kind: types.SearchPost, ID: 2, Title: "More Go", URL: "/posts/more-go", Snippet: "More"},
+	}
+
+	rr := serveSearch(t, store, "/search?q=go&size=1")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if len(store.searches) != 1 || store.searches[0] != "go" {
+		t.Errorf("Expected one search for %q, but got %q", "go", store.searches)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, `<a href="/posts/go-tips">Go tips</a>`) {
+		t.Errorf("Expected a link to the first result, but got %s", body)
+	}
+	if !strings.Contains(body, "Some <mark>&lt;go&gt;</mark> tips") {
+		t.Errorf("Expected the escaped match to be marked, but got %s", body)
+	}
+	if strings.Contains(body, "More Go") {
+		t.Errorf("Expected only the first page of results, but got %s", body)
+	}
+	if !strings.Contains(body, `hx-get="/search?page=2&amp;q=go&amp;size=1"`) {
+		t.Errorf("Expected a link to the second page, but got %s", body)
+	}
+}
+
+func TestSearchWithoutQuery(t *testing.T) {
+	store := newFakeStore()
+
+	rr := serveSearch(t, store, "/search?q=+")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if len(store.searches) != 0 {
+		t.Errorf("Expected no search for an empty query, but got %q", store.searches)
+	}
+	if strings.Contains(rr.Body.String(), "No results") {
+		t.Errorf("Expected no empty results message without a query, but got %s", rr.Body)
+	}
+}
+
+func TestSearchTruncatesLongQueries(t *testing.T) {
+	store := newFakeStore()
+
+	rr := serveSearch(t, store, "/search?q="+strings.Repeat("é", 250))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if len(store.searches) != 1 || store.searches[0] != strings.Repeat("é", 200) {
+		t.Errorf("Expected the query to be cut to 200 characters, but got %q", store.searches)
+	}
+	if !strings.Contains(rr.Body.String(), "No results") {
+		t.Errorf("Expected the empty results message, but got %s", rr.Body)
+	}
+}
@@ -0,0 +1,328 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go_api/database"
+	"go_api/mail"
+	"go_api/pagination"
+	"go_api/types"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeStore implements the store methods the tests need; calling any other
+// method panics on the nil embedded interface.
+type fakeStore struct {
+	database.Methods
+	mu            sync.Mutex
+	user          *types.User
+	role          types.MemberRole
+	cards         map[int]*types.Card
+	uploads       map[string]*types.Upload
+	dueCards      []*types.Card
+	notifications []*types.Notification
+	posts         []*types.Post
+	tags          []*types.Tag
+	categories    []*types.Category
+	attachments   map[int]*types.Attachment
+	boards        map[int]*types.Board
+	cardEvents    map[int][]*types.CardEvent
+	searchResults []*types.SearchResult
+	searches      []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		user:        &types.User{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"},
+		role:        types.MemberEditor,
+		cards:       map[int]*types.Card{},
+		uploads:     map[string]*types.Upload{},
+		attachments: map[int]*types.Attachment{},
+		boards:      map[int]*types.Board{},
+		cardEvents:  map[int][]*types.CardEvent{},
+	}
+}
+
+func (s *fakeStore) GetUserByEmail(email string) (*types.User, error) {
+	if s.user == nil || s.user.Email != email {
+		return nil, fmt.Errorf("user %s not found", email)
+	}
+	return s.user, nil
+}
+
+// GetUser returns the store's user, or another user with a made up email.
+func (s *fakeStore) GetUser(id int) (*types.User, error) {
+	if s.user != nil && s.user.ID == id {
+		return s.user, nil
+	}
+	return &types.User{ID: id, FirstName: "User", LastName: fmt.Sprint(id), Email: fmt.Sprintf("user%d@example.com", id)}, nil
+}
+
+func (s *fakeStore) GetBoard(id int) (*types.Board, error) {
+	board, ok := s.boards[id]
+	if !ok {
+		return nil, fmt.Errorf("board %d not found", id)
+	}
+	return board, nil
+}
+
+func (s *fakeStore) GetBoardRole(boardID int, userID int) (types.MemberRole, error) {
+	if _, err := s.GetBoard(boardID); err != nil {
+		return "", err
+	}
+	return s.role, nil
+}
+
+func (s *fakeStore) GetBoardMembers(boardID int) ([]*types.Member, error) {
+	return []*types.Member{}, nil
+}
+
+func (s *fakeStore) GetCardBoardRole(cardID int, userID int) (int, types.MemberRole, error) {
+	card, err := s.GetCard(cardID)
+	if err != nil {
+		return 0, "", err
+	}
+	return card.BoardID, s.role, nil
+}
+
+func (s *fakeStore) GetCard(id int) (*types.Card, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card, ok := s.cards[id]
+	if !ok {
+		return nil, fmt.Errorf("card %d not found", id)
+	}
+	return card, nil
+}
+
+func (s *fakeStore) GetBoardUsers(boardID int) ([]*types.User, error) {
+	return []*types.User{s.user}, nil
+}
+
+// GetCardTree returns the card on its own; the fake has no sub-cards.
+func (s *fakeStore) GetCardTree(id int) (*types.Card, error) {
+	return s.GetCard(id)
+}
+
+func (s *fakeStore) GetCardEvents(cardID int) ([]*types.CardEvent, error) {
+	return s.cardEvents[cardID], nil
+}
+
+func (s *fakeStore) CreateCardComment(event *types.CardEvent) error {
+	events := s.cardEvents[event.CardID]
+	event.ID = len(events) + 1
+	s.cardEvents[event.CardID] = append(events, event)
+	return nil
+}
+
+func (s *fakeStore) GetLabels(boardID int) ([]*types.Label, error) {
+	return []*types.Label{}, nil
+}
+
+func (s *fakeStore) CreateUpload(upload *types.Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.uploads[upload.ID] = upload
+	return nil
+}
+
+// GetUpload returns a copy, as reading it from the database would.
+func (s *fakeStore) GetUpload(id string) (*types.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		return nil, fmt.Errorf("upload %s not found", id)
+	}
+	stored := *upload
+	return &stored, nil
+}
+
+func (s *fakeStore) UpdateUploadOffset(id string, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		return fmt.Errorf("upload %s not found", id)
+	}
+	upload.Offset = offset
+	upload.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *fakeStore) DeleteUpload(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.uploads, id)
+	return nil
+}
+
+func (s *fakeStore) GetStaleUploads(updatedBefore time.Time) ([]*types.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploads := []*types.Upload{}
+	for _, upload := range s.uploads {
+		if upload.UpdatedAt.Before(updatedBefore) {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (s *fakeStore) GetPublishedPosts(limit int) ([]*types.Post, error) {
+	return s.posts, nil
+}
+
+func (s *fakeStore) GetPost(id int) (*types.Post, error) {
+	for _, post := range s.posts {
+		if post.ID == id {
+			return post, nil
+		}
+	}
+	return nil, fmt.Errorf("post %d not found", id)
+}
+
+func (s *fakeStore) GetPostBySlug(slug string) (*types.Post, error) {
+	for _, post := range s.posts {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+	return nil, fmt.Errorf("post %s not found", slug)
+}
+
+// SearchPosts records the query and pages through searchResults the way
+// the database does.
+func (s *fakeStore) SearchPosts(query string, params pagination.Params) ([]*types.SearchResult, bool, error) {
+	s.searches = append(s.searches, query)
+	offset := min((params.Page-1)*params.Size, len(s.searchResults))
+	results, more := pagination.Trim(s.searchResults[offset:], params.Size)
+	return results, more, nil
+}
+
+func (s *fakeStore) DeletePost(id int) error {
+	posts := []*types.Post{}
+	for _, post := range s.posts {
+		if post.ID != id {
+			posts = append(posts, post)
+		}
+	}
+	s.posts = posts
+	return nil
+}
+
+func (s *fakeStore) CountCommentsSince(userID int, since time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) HasApprovedComments(userID int) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeStore) CreateComment(comment *types.Comment) error {
+	comment.ID = 1
+	return nil
+}
+
+func (s *fakeStore) GetPostComments(postID int, viewerID *int) ([]*types.Comment, error) {
+	return []*types.Comment{}, nil
+}
+
+func (s *fakeStore) GetTags() ([]*types.Tag, error) {
+	return s.tags, nil
+}
+
+func (s *fakeStore) GetPublicCategories() ([]*types.Category, error) {
+	return s.categories, nil
+}
+
+func (s *fakeStore) GetCardsDueBetween(from time.Time, to time.Time) ([]*types.Card, error) {
+	return s.dueCards, nil
+}
+
+// CreateNotification stores the notification for email only, as the store
+// does for a user who turned in-app notifications off, so no bell update is
+// published.
+func (s *fakeStore) CreateNotification(notification *types.Notification) error {
+	notification.ID = len(s.notifications) + 1
+	notification.InApp = false
+	s.notifications = append(s.notifications, notification)
+	return nil
+}
+
+func (s *fakeStore) GetAttachments(kind types.AttachmentKind, ownerID int) ([]*types.Attachment, error) {
+	attachments := []*types.Attachment{}
+	for _, attachment := range s.attachments {
+		id := attachment.CardID
+		if kind == types.AttachmentPost {
+			id = attachment.PostID
+		}
+		if id != nil && *id == ownerID {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func (s *fakeStore) GetAttachment(id int) (*types.Attachment, error) {
+	attachment, ok := s.attachments[id]
+	if !ok {
+		return nil, fmt.Errorf("attachment %d not found", id)
+	}
+	return attachment, nil
+}
+
+func (s *fakeStore) DeleteAttachment(id int) error {
+	delete(s.attachments, id)
+	return nil
+}
+
+// fakeMailer hands every message it is asked to send to sent.
+type fakeMailer struct {
+	sent chan mail.Message
+}
+
+func (m *fakeMailer) Send(msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// logIn adds the access token cookie of the store's user to r.
+func logIn(t *testing.T, r *http.Request, store *fakeStore) {
+	claims := &types.LoginResponse{
+		Email: store.user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		t.Fatalf("Expected no error signing the token, but got %v", err)
+	}
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+}
+
+// inTempDir runs the test in an empty working directory, where handlers
+// writing to ./tmp cannot touch the repository.
+func inTempDir(t *testing.T) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(workDir) })
+}
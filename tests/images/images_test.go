@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"go_api/images"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessCreatesSquareVariants(t *testing.T) {
+	format, renditions, err := images.Process(bytes.NewReader(encodePNG(t, 640, 480)))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if format != "png" {
+		t.Errorf("Expected format to be %s, but got %s", "png", format)
+	}
+
+	if len(renditions) != len(images.Variants) {
+		t.Fatalf("Expected %d renditions, but got %d", len(images.Variants), len(renditions))
+	}
+
+	for _, rendition := range renditions {
+		cfg, err := png.DecodeConfig(bytes.NewReader(rendition.Data))
+		if err != nil {
+			t.Fatalf("Expected a valid png for %s, but got %v", rendition.Variant.Name, err)
+		}
+		if cfg.Width != rendition.Variant.Size || cfg.Height != rendition.Variant.Size {
+			t.Errorf("Expected %s to be %dx%d, but got %dx%d", rendition.Variant.Name,
+				rendition.Variant.Size, rendition.Variant.Size, cfg.Width, cfg.Height)
+		}
+	}
+}
+
+func TestProcessRejectsOversizedImages(t *testing.T) {
+	_, _, err := images.Process(bytes.NewReader(encodePNG(t, images.MaxDimension+1, 1)))
+	if err != images.ErrTooLarge {
+		t.Errorf("Expected %v, but got %v", images.ErrTooLarge, err)
+	}
+}
+
+func TestProcessRejectsUnknownFormats(t *testing.T) {
+	_, _, err := images.Process(bytes.NewReader([]byte("not an image")))
+	if err != images.ErrUnsupportedFormat {
+		t.Errorf("Expected %v, but got %v", images.ErrUnsupportedFormat, err)
+	}
+}
+
+func TestVariantPath(t *testing.T) {
+	got := images.VariantPath("/static/uploads/123.jpg", "thumb")
+	if got != "/static/uploads/123_thumb.jpg" {
+		t.Errorf("Expected %s, but got %s", "/static/uploads/123_thumb.jpg", got)
+	}
+
+	if images.VariantPath("", "thumb") != "" {
+		t.Error("Expected an empty path to stay empty")
+	}
+}
+
+func TestVariantKeys(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"avatars/ab12.png", []string{"avatars/ab12_thumb.png", "avatars/ab12_large.png"}},
+		{"123.png", []string{"123.png", "123.png"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		got := images.VariantKeys(test.key)
+		if strings.Join(got, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Expected %v for %q, but got %v", test.expected, test.key, got)
+		}
+	}
+}
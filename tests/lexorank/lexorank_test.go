@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"go_api/lexorank"
+)
+
+func TestBetween(t *testing.T) {
+	cases := []struct {
+		lower, upper string
+	}{
+		{"", ""},
+		{"", "1"},
+		{"", "001"},
+		{"i", ""},
+		{"z", ""},
+		{"zz", ""},
+		{"a", "b"},
+		{"a", "a1"},
+		{"a1", "a2"},
+		{"a", "az"},
+		{"0001", "0002"},
+		{"000010i", "000011i"},
+	}
+	for _, c := range cases {
+		key, err := lexorank.Between(c.lower, c.upper)
+		if err != nil {
+			t.Errorf("Expected no error for (%q, %q), but got %v", c.lower, c.upper, err)
+			continue
+		}
+		if !lexorank.Valid(key) {
+			t.Errorf("Expected a valid key for (%q, %q), but got %q", c.lower, c.upper, key)
+		}
+		if key <= c.lower || (c.upper != "" && key >= c.upper) {
+			t.Errorf("Expected a key between %q and %q, but got %q", c.lower, c.upper, key)
+		}
+	}
+}
+
+func TestBetweenRejectsInvalidInput(t *testing.T) {
+	cases := []struct {
+		lower, upper string
+		err          error
+	}{
+		{"b", "a", lexorank.ErrInvalidRange},
+		{"a", "a", lexorank.ErrInvalidRange},
+		{"a0", "", lexorank.ErrInvalidKey},
+		{"A", "", lexorank.ErrInvalidKey},
+		{"", "a-b", lexorank.ErrInvalidKey},
+	}
+	for _, c := range cases {
+		if _, err := lexorank.Between(c.lower, c.upper); err != c.err {
+			t.Errorf("Expected %v for (%q, %q), but got %v", c.err, c.lower, c.upper, err)
+		}
+	}
+}
+
+func TestRepeatedInsertsKeepOrder(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := 0; i < 500; i++ {
+		at := random.Intn(len(keys) + 1)
+		lower, upper := "", ""
+		if at > 0 {
+			lower = keys[at-1]
+		}
+		if at < len(keys) {
+			upper = keys[at]
+		}
+
+		key, err := lexorank.Between(lower, upper)
+		if err != nil {
+			t.Fatalf("Expected no error for (%q, %q), but got %v", lower, upper, err)
+		}
+		keys = append(keys[:at], append([]string{key}, keys[at:]...)...)
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("Expected keys to stay sorted, but got %v", keys)
+	}
+}
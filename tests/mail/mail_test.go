@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/mail"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := mail.Message{
+		To:      []string{"jane@example.com"},
+		Subject: "New comment on “Go”",
+		Body:    "Hello\nWorld",
+	}
+
+	data, err := msg.Bytes("blog@example.com", time.Date(2023, 11, 18, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	doc := string(data)
+	for _, expected := range []string{
+		"From: blog@example.com\r\n",
+		"To: jane@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Sat, 18 Nov 2023 10:00:00 +0000\r\n",
+		"\r\n\r\nHello\r\nWorld",
+	} {
+		if !strings.Contains(doc, expected) {
+			t.Errorf("Expected message to contain %q, but got %q", expected, doc)
+		}
+	}
+}
+
+func TestMessageBytesRejectsHeaderInjection(t *testing.T) {
+	msg := mail.Message{
+		To:      []string{"jane@example.com"},
+		Subject: "Hi\r\nBcc: everyone@example.com",
+	}
+
+	if _, err := msg.Bytes("blog@example.com", time.Now()); err != mail.ErrInvalidHeader {
+		t.Errorf("Expected ErrInvalidHeader, but got %v", err)
+	}
+}
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := &mail.LogSender{Logger: log.New(&buf, "", 0)}
+
+	if err := sender.Send(mail.Message{To: []string{"a@example.com"}, Subject: "Hi", Body: "Body"}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "a@example.com") || !strings.Contains(buf.String(), "Hi") {
+		t.Errorf("Expected the message to be logged, but got %q", buf.String())
+	}
+}
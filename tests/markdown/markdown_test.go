@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/markdown"
+)
+
+func TestRenderTables(t *testing.T) {
+	html, err := markdown.Render("| a | b |\n|---|---|\n| 1 | 2 |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(html, "<table>") || !strings.Contains(html, "<td>1</td>") {
+		t.Errorf("Expected a rendered table, but got %s", html)
+	}
+}
+
+func TestRenderHighlightsFencedCode(t *testing.T) {
+	html, err := markdown.Render("```go\nfunc main() {}\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(html, `class="chroma"`) {
+		t.Errorf("Expected highlighted code, but got %s", html)
+	}
+	if strings.Contains(html, "style=") {
+		t.Errorf("Expected highlighting to use classes only, but got %s", html)
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	html, err := markdown.Render("<script>alert(1)</script>\n\n[x](javascript:alert(1))\n\n<img src=x onerror=alert(1)>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unsafe := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(html, unsafe) {
+			t.Errorf("Expected %q to be removed, but got %s", unsafe, html)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	excerpt := markdown.Excerpt("<p>Hello <strong>big</strong> &amp; wide world</p>", 15)
+	if excerpt != "Hello big &…" {
+		t.Errorf("Expected %q, but got %q", "Hello big &…", excerpt)
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_api/pagination"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pagination.Cursor{Time: time.Date(2023, 11, 20, 8, 30, 15, 123456789, time.UTC), ID: 42}
+
+	decoded, err := pagination.Decode(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if !decoded.Time.Equal(cursor.Time) || decoded.ID != cursor.ID {
+		t.Errorf("Expected %+v, but got %+v", cursor, decoded)
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, token := range []string{"not base64!", "bm9wZQ"} {
+		if _, err := pagination.Decode(token); err != pagination.ErrInvalidCursor {
+			t.Errorf("Expected ErrInvalidCursor for %q, but got %v", token, err)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cursor := pagination.Cursor{Time: time.Unix(1700000000, 0).UTC(), ID: 7}
+
+	var params pagination.Params
+	handler := pagination.Middleware(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = pagination.FromContext(r.Context(), 10)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/posts?size=500&cursor="+cursor.Encode(), nil))
+
+	if params.Size != pagination.MaxSize {
+		t.Errorf("Expected Size to be capped at %d, but got %d", pagination.MaxSize, params.Size)
+	}
+
+	if params.After == nil || params.After.ID != 7 {
+		t.Fatalf("Expected the cursor to be decoded, but got %+v", params.After)
+	}
+
+	if params.First() {
+		t.Error("Expected a request with a cursor not to be the first page")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?page=3&cursor=garbage", nil))
+
+	if params.Size != 10 || params.After != nil || params.Page != 3 {
+		t.Errorf("Expected default size and no cursor on page 3, but got %+v", params)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	items, more := pagination.Trim([]int{1, 2, 3}, 2)
+	if len(items) != 2 || !more {
+		t.Errorf("Expected 2 items and more, but got %v and %v", items, more)
+	}
+
+	items, more = pagination.Trim([]int{1, 2}, 2)
+	if len(items) != 2 || more {
+		t.Errorf("Expected 2 items and no more, but got %v and %v", items, more)
+	}
+}
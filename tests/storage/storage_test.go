@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go_api/storage"
+)
+
+// fakeS3 is a minimal MinIO-style stand-in that keeps objects in memory and
+// only accepts requests carrying a SigV4 Authorization header.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	bucket := strings.TrimSuffix(r.URL.Path, "/") + "/"
+	prefix := bucket + r.URL.Query().Get("prefix")
+
+	fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>`)
+	for path, data := range f.objects {
+		if strings.HasPrefix(path, prefix) {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>2023-11-10T12:00:00.000Z</LastModified><Size>%d</Size></Contents>`,
+				strings.TrimPrefix(path, bucket), len(data))
+		}
+	}
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+func testBlobStore(t *testing.T, store storage.BlobStore) {
+	if err := store.Put("avatars/1.jpg", strings.NewReader("hello"), "image/jpeg"); err != nil {
+		t.Fatalf("Expected no error on Put, but got %v", err)
+	}
+
+	if err := store.Put("other/2.jpg", strings.NewReader("other"), "image/jpeg"); err != nil {
+		t.Fatalf("Expected no error on Put, but got %v", err)
+	}
+
+	blobs, err := store.List("avatars/")
+	if err != nil {
+		t.Fatalf("Expected no error on List, but got %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].Key != "avatars/1.jpg" || blobs[0].Size != 5 {
+		t.Errorf("Expected List to return only avatars/1.jpg, but got %+v", blobs)
+	}
+
+	rc, err := store.Get("avatars/1.jpg")
+	if err != nil {
+		t.Fatalf("Expected no error on Get, but got %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("Expected content to be %s, but got %s", "hello", data)
+	}
+
+	if err := store.Delete("avatars/1.jpg"); err != nil && err != storage.ErrNotFound {
+		t.Fatalf("Expected no error on Delete, but got %v", err)
+	}
+
+	if _, err := store.Get("avatars/1.jpg"); err != storage.ErrNotFound {
+		t.Errorf("Expected %v after delete, but got %v", storage.ErrNotFound, err)
+	}
+
+	if err := store.Put("../escape.jpg", strings.NewReader("x"), ""); err != storage.ErrInvalidKey {
+		t.Errorf("Expected %v, but got %v", storage.ErrInvalidKey, err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store := storage.NewLocalStore(t.TempDir(), "/static/uploads/")
+	testBlobStore(t, store)
+
+	if url := store.URL("avatars/1.jpg"); url != "/static/uploads/avatars/1.jpg" {
+		t.Errorf("Expected URL to be %s, but got %s", "/static/uploads/avatars/1.jpg", url)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "uploads",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PublicURL: "https://cdn.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store)
+
+	if err := store.Put("avatars/2.png", strings.NewReader("png"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.types["/uploads/avatars/2.png"] != "image/png" {
+		t.Errorf("Expected object to be stored under the bucket path, got %v", fake.types)
+	}
+
+	if url := store.URL("avatars/2.png"); url != "https://cdn.example.com/avatars/2.png" {
+		t.Errorf("Expected URL to be %s, but got %s", "https://cdn.example.com/avatars/2.png", url)
+	}
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/types"
+)
+
+func renderBoardColumns(t *testing.T, data handlers.BoardResponse) string {
+	return renderTemplate(t, data, "workspace/boardColumns.html", "workspace/cardDraggable.html", "workspace/card.html")
+}
+
+func testBoard() *types.Board {
+	return &types.Board{ID: 2, Name: "Roadmap", Columns: []*types.Column{
+		{ID: 5, BoardID: 2, Name: "Todo", Cards: []*types.Card{}},
+		{ID: 6, BoardID: 2, Name: "Done", Cards: []*types.Card{}},
+	}}
+}
+
+func TestBoardColumnsCardFormsFollowRole(t *testing.T) {
+	tests := []struct {
+		role     types.MemberRole
+		expected int
+	}{
+		{types.MemberViewer, 0},
+		{types.MemberEditor, 2},
+		{types.MemberAdmin, 2},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		html := renderBoardColumns(t, handlers.BoardResponse{Board: testBoard(), Role: test.role})
+		if count := strings.Count(html, `class="card-create"`); count != test.expected {
+			t.Errorf("Expected %d card forms for role %q, but got %d", test.expected, test.role, count)
+		}
+	}
+}
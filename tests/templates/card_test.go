@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/handlers"
+	"go_api/templates"
+	"go_api/types"
+)
+
+func renderTemplate(t *testing.T, data any, patterns ...string) string {
+	tmpl, err := template.ParseFS(templates.Templates, patterns...)
+	if err != nil {
+		t.Fatalf("Expected no error parsing the templates, but got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("Expected no error rendering %s, but got %v", patterns[0], err)
+	}
+	return buf.String()
+}
+
+func TestCardShowsItsFields(t *testing.T) {
+	assigneeID := 4
+	dueOn := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	card := &types.Card{
+		ID:         7,
+		Name:       "Ship it",
+		AssigneeID: &assigneeID,
+		Assignee:   "Ada Lovelace",
+		DueOn:      &dueOn,
+		Priority:   types.PriorityHigh,
+		Labels:     []*types.Label{{ID: 3, Name: "Bug", Color: "#ff0000"}},
+	}
+
+	html := renderTemplate(t, card, "workspace/card.html")
+
+	for _, expected := range []string{
+		`class="draggable-item overdue"`,
+		`data-assignee="4"`,
+		`data-labels=" 3 "`,
+		`title="Bug"`,
+		`card-priority-high`,
+		`<span class="card-due" title="Due date">Jan 2</span>`,
+		`title="Ada Lovelace">AL</span>`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected the card to contain %q, but got %s", expected, html)
+		}
+	}
+}
+
+func TestCardHidesEmptyFields(t *testing.T) {
+	card := &types.Card{ID: 7, Name: "Ship it", Priority: types.PriorityNone}
+
+	html := renderTemplate(t, card, "workspace/card.html")
+
+	for _, unexpected := range []string{"overdue\"", "card-labels", "card-priority", "card-due", "avatar"} {
+		if strings.Contains(html, unexpected) {
+			t.Errorf("Expected the card not to contain %q, but got %s", unexpected, html)
+		}
+	}
+}
+
+func TestCardModalFollowsCanEdit(t *testing.T) {
+	patterns := []string{"card/cardModal.html", "card/cardForm.html", "card/cardChecklist.html", "card/cardActivity.html", "card/cardEvent.html"}
+	card := &types.Card{ID: 7, Name: "Ship it", Priority: types.PriorityNone}
+
+	for _, canEdit := range []bool{true, false} {
+		data := handlers.CardDetailsResponse{CardFormResponse: handlers.CardFormResponse{
+			Card:       card,
+			Priorities: types.CardPriorities,
+			CanEdit:    canEdit,
+		}}
+		html := renderTemplate(t, data, patterns...)
+
+		if !strings.Contains(html, `hx-put="/workspace/7"`) || !strings.Contains(html, `value="Ship it"`) {
+			t.Errorf("Expected the modal to contain the card form, but got %s", html)
+		}
+		if deletable := strings.Contains(html, `hx-delete="/workspace/7"`); deletable != canEdit {
+			t.Errorf("Expected the delete button to be shown to be %v, but got %v", canEdit, deletable)
+		}
+		if disabled := strings.Contains(html, `class="card-fieldset" disabled`); disabled == canEdit {
+			t.Errorf("Expected the form to be disabled to be %v, but got %v", !canEdit, disabled)
+		}
+	}
+}
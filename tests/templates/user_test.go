@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"go_api/handlers"
+	"go_api/templates"
+	"go_api/types"
+)
+
+func TestUsersPaginatedLoadsTheNextPage(t *testing.T) {
+	tmpl, err := template.ParseFS(templates.Templates, "user/usersPaginated.html", "user/userRow.html")
+	if err != nil {
+		t.Fatalf("Expected no error parsing the templates, but got %v", err)
+	}
+
+	users := []*types.User{{ID: 1, FirstName: "Ada", ImageURLs: map[string]string{}}, {ID: 2, FirstName: "Bob", ImageURLs: map[string]string{}}}
+	tests := []struct {
+		nextURL  string
+		expected bool
+	}{
+		{"/users?cursor=abc", true},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, handlers.UsersResponse{Users: users, NextURL: test.nextURL}); err != nil {
+			t.Fatalf("Expected no error rendering the users, but got %v", err)
+		}
+		html := buf.String()
+
+		if count := strings.Count(html, `id="datarow-`); count != len(users) {
+			t.Errorf("Expected %d rows, but got %d", len(users), count)
+		}
+		if loadsMore := strings.Contains(html, `hx-get="/users?cursor=abc"`); loadsMore != test.expected {
+			t.Errorf("Expected loading the next page to be %v for %q, but got %v", test.expected, test.nextURL, loadsMore)
+		}
+	}
+}
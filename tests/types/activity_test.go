@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/types"
+)
+
+func TestNewCardComment(t *testing.T) {
+	comment, err := types.NewCardComment(4, 7, "  Blocked on review ")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if comment.Kind != types.EventComment || comment.Body != "Blocked on review" {
+		t.Errorf("Expected a trimmed comment event, but got %+v", comment)
+	}
+	if comment.UserID == nil || *comment.UserID != 7 || comment.CardID != 4 {
+		t.Errorf("Expected the comment to belong to user 7 on card 4, but got %+v", comment)
+	}
+
+	if _, err := types.NewCardComment(4, 7, " "); err == nil {
+		t.Errorf("Expected an error for an empty comment, but got nil")
+	}
+	if _, err := types.NewCardComment(4, 7, strings.Repeat("x", types.MaxCommentLength+1)); err == nil {
+		t.Errorf("Expected an error for a long comment, but got nil")
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+
+	"go_api/types"
+)
+
+func TestAttachmentLimitsAllows(t *testing.T) {
+	tests := []struct {
+		kind        types.AttachmentKind
+		contentType string
+		expected    bool
+	}{
+		{types.AttachmentPost, "image/png", true},
+		{types.AttachmentPost, "application/pdf", true},
+		{types.AttachmentPost, "text/plain; charset=utf-8", false},
+		{types.AttachmentCard, "text/plain; charset=utf-8", true},
+		{types.AttachmentCard, "application/zip", true},
+		{types.AttachmentCard, "text/html; charset=utf-8", false},
+	}
+
+	for _, test := range tests {
+		if allowed := types.AttachmentRules[test.kind].Allows(test.contentType); allowed != test.expected {
+			t.Errorf("Expected %s attachments allowing %q to be %v, but got %v", test.kind, test.contentType, test.expected, allowed)
+		}
+	}
+}
+
+func TestNewAttachment(t *testing.T) {
+	post := types.NewAttachment(types.AttachmentPost, 7, "key", "photo.png", "image/png", 10)
+	if post.PostID == nil || *post.PostID != 7 || post.CardID != nil {
+		t.Errorf("Expected a post attachment on post 7, but got %+v", post)
+	}
+	if !post.IsImage() {
+		t.Errorf("Expected a png attachment to be an image")
+	}
+
+	card := types.NewAttachment(types.AttachmentCard, 3, "key", "notes.pdf", "application/pdf", 10)
+	if card.CardID == nil || *card.CardID != 3 || card.PostID != nil {
+		t.Errorf("Expected a card attachment on card 3, but got %+v", card)
+	}
+	if card.IsImage() {
+		t.Errorf("Expected a pdf attachment not to be an image")
+	}
+}
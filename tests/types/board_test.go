@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"go_api/types"
+)
+
+func TestNewBoard(t *testing.T) {
+	board, err := types.NewBoard("  Sprint 12 ")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if board.Name != "Sprint 12" {
+		t.Errorf("Expected name %q, but got %q", "Sprint 12", board.Name)
+	}
+	if len(board.Columns) != len(types.DefaultColumns) {
+		t.Fatalf("Expected %d columns, but got %d", len(types.DefaultColumns), len(board.Columns))
+	}
+	for i, column := range board.Columns {
+		if column.Position != i {
+			t.Errorf("Expected column %q at position %d, but got %d", column.Name, i, column.Position)
+		}
+	}
+
+	if _, err := types.NewBoard(" "); err == nil {
+		t.Errorf("Expected an error for an empty name, but got nil")
+	}
+	if _, err := types.NewBoard(strings.Repeat("x", types.MaxBoardNameLength+1)); err == nil {
+		t.Errorf("Expected an error for a long name, but got nil")
+	}
+}
+
+func TestParseMoveCardRequest(t *testing.T) {
+	move, err := types.ParseMoveCardRequest(url.Values{
+		"card":    {"7"},
+		"column":  {"3"},
+		"after":   {"5"},
+		"before":  {""},
+		"version": {"12"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if move.CardID != 7 || move.ColumnID != 3 || move.Version != 12 {
+		t.Errorf("Expected card 7 in column 3 at version 12, but got %+v", move)
+	}
+	if move.AfterID == nil || *move.AfterID != 5 {
+		t.Errorf("Expected card to follow card 5, but got %v", move.AfterID)
+	}
+	if move.BeforeID != nil {
+		t.Errorf("Expected no card after the moved card, but got %d", *move.BeforeID)
+	}
+}
+
+func TestParseMoveCardRequestRejectsInvalidInput(t *testing.T) {
+	invalid := []url.Values{
+		{"column": {"3"}, "version": {"1"}},
+		{"card": {"7"}, "column": {"x"}, "version": {"1"}},
+		{"card": {"7"}, "column": {"3"}},
+		{"card": {"7"}, "column": {"3"}, "version": {"1"}, "after": {"seven"}},
+		{"card": {"7"}, "column": {"3"}, "version": {"1"}, "before": {"7"}},
+	}
+	for _, form := range invalid {
+		if _, err := types.ParseMoveCardRequest(form); err == nil {
+			t.Errorf("Expected an error for %v, but got nil", form)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/types"
+)
+
+func TestNewCardValidatesName(t *testing.T) {
+	card, err := types.NewCard(3, "  Write docs ", " details ", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if card.Name != "Write docs" || card.Content != "details" {
+		t.Errorf("Expected trimmed name and content, but got %q and %q", card.Name, card.Content)
+	}
+
+	if _, err := types.NewCard(3, " ", "", nil); err == nil {
+		t.Errorf("Expected an error for an empty name, but got nil")
+	}
+	if _, err := types.UpdateCard(1, strings.Repeat("x", types.MaxCardNameLength+1), ""); err == nil {
+		t.Errorf("Expected an error for a long name, but got nil")
+	}
+}
+
+func TestNestCards(t *testing.T) {
+	one, two, missing := 1, 2, 99
+	cards := []*types.Card{
+		{ID: 1},
+		{ID: 2, Parent: &one, Done: true},
+		{ID: 3, Parent: &two},
+		{ID: 4, Parent: &one},
+		{ID: 5},
+		{ID: 6, Parent: &missing},
+	}
+
+	roots := types.NestCards(cards)
+	if len(roots) != 3 || roots[0].ID != 1 || roots[1].ID != 5 || roots[2].ID != 6 {
+		t.Fatalf("Expected roots 1, 5 and 6, but got %v", roots)
+	}
+
+	root := roots[0]
+	if len(root.Children) != 2 || root.Children[0].ID != 2 || root.Children[1].ID != 4 {
+		t.Errorf("Expected children 2 and 4 in order, but got %v", root.Children)
+	}
+	if root.ItemCount() != 3 {
+		t.Errorf("Expected 3 items, but got %d", root.ItemCount())
+	}
+	if root.DoneCount() != 1 {
+		t.Errorf("Expected 1 done item, but got %d", root.DoneCount())
+	}
+	if !root.Contains(3) || root.Contains(5) {
+		t.Errorf("Expected card 1 to contain card 3 but not card 5")
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	commentType "go_api/types"
+)
+
+func TestNewComment(t *testing.T) {
+	comment, err := commentType.NewComment(1, nil, 2, "  Nice post  ", commentType.CommentPending)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if comment.Body != "Nice post" {
+		t.Errorf("Expected Body to be trimmed, but got %q", comment.Body)
+	}
+
+	if comment.Status != commentType.CommentPending {
+		t.Errorf("Expected Status to be %s, but got %s", commentType.CommentPending, comment.Status)
+	}
+
+	if _, err := commentType.NewComment(1, nil, 2, "   ", commentType.CommentPending); err == nil {
+		t.Error("Expected an empty comment to fail")
+	}
+
+	long := strings.Repeat("a", commentType.MaxCommentLength+1)
+	if _, err := commentType.NewComment(1, nil, 2, long, commentType.CommentPending); err == nil {
+		t.Error("Expected an overlong comment to fail")
+	}
+}
+
+func TestThreadComments(t *testing.T) {
+	one, two, missing := 1, 2, 99
+	comments := []*commentType.Comment{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3, ParentID: &one},
+		{ID: 4, ParentID: &two},
+		{ID: 5, ParentID: &one},
+		{ID: 6, ParentID: &missing},
+	}
+
+	threads := commentType.ThreadComments(comments)
+
+	if len(threads) != 2 {
+		t.Fatalf("Expected 2 threads, but got %d", len(threads))
+	}
+
+	if len(threads[0].Replies) != 2 || threads[0].Replies[0].ID != 3 || threads[0].Replies[1].ID != 5 {
+		t.Errorf("Expected replies 3 and 5 under comment 1, but got %+v", threads[0].Replies)
+	}
+
+	if len(threads[1].Replies) != 1 || threads[1].Replies[0].ID != 4 {
+		t.Errorf("Expected reply 4 under comment 2, but got %+v", threads[1].Replies)
+	}
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go_api/types"
+)
+
+func TestNewLabel(t *testing.T) {
+	label, err := types.NewLabel(2, " bug ", "#EB5A46")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if label.Name != "bug" || label.Color != "#eb5a46" || label.BoardID != 2 {
+		t.Errorf("Expected a trimmed label with a lowercase color, but got %+v", label)
+	}
+
+	if _, err := types.NewLabel(2, "", "#eb5a46"); err == nil {
+		t.Errorf("Expected an error for an empty name, but got nil")
+	}
+	if _, err := types.NewLabel(2, "bug", "red"); err == nil {
+		t.Errorf("Expected an error for an invalid color, but got nil")
+	}
+}
+
+func TestCardIsOverdue(t *testing.T) {
+	now := time.Date(2023, 11, 25, 15, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2023, 11, 24, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC)
+
+	if !(&types.Card{DueOn: &yesterday}).IsOverdue(now) {
+		t.Errorf("Expected a card due yesterday to be overdue, but it was not")
+	}
+	if (&types.Card{DueOn: &today}).IsOverdue(now) {
+		t.Errorf("Expected a card due today not to be overdue, but it was")
+	}
+	if (&types.Card{DueOn: &yesterday, Done: true}).IsOverdue(now) {
+		t.Errorf("Expected a done card not to be overdue, but it was")
+	}
+	if (&types.Card{}).IsOverdue(now) {
+		t.Errorf("Expected a card without a due date not to be overdue, but it was")
+	}
+}
+
+func TestUpdateCardRequestLabels(t *testing.T) {
+	var single, many types.UpdateCardRequest
+	if err := json.Unmarshal([]byte(`{"labels": "3"}`), &single); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"labels": ["3", "4"]}`), &many); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(single.Labels) != 1 || len(many.Labels) != 2 {
+		t.Errorf("Expected 1 and 2 labels, but got %v and %v", single.Labels, many.Labels)
+	}
+
+	if !types.PriorityHigh.Valid() || types.CardPriority("urgent").Valid() {
+		t.Errorf("Expected only known priorities to be valid")
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/types"
+)
+
+func TestMemberRoleAllows(t *testing.T) {
+	tests := []struct {
+		role     types.MemberRole
+		required types.MemberRole
+		expected bool
+	}{
+		{types.MemberViewer, types.MemberViewer, true},
+		{types.MemberViewer, types.MemberEditor, false},
+		{types.MemberEditor, types.MemberViewer, true},
+		{types.MemberEditor, types.MemberAdmin, false},
+		{types.MemberAdmin, types.MemberEditor, true},
+		{"", types.MemberViewer, false},
+		{"owner", types.MemberViewer, false},
+	}
+
+	for _, test := range tests {
+		if allowed := test.role.Allows(test.required); allowed != test.expected {
+			t.Errorf("Expected %q allowing %q to be %v, but got %v", test.role, test.required, test.expected, allowed)
+		}
+	}
+}
+
+func TestNewTeam(t *testing.T) {
+	team, err := types.NewTeam(" Core ")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if team.Name != "Core" {
+		t.Errorf("Expected a trimmed name, but got %q", team.Name)
+	}
+
+	if _, err := types.NewTeam(" "); err == nil {
+		t.Errorf("Expected an error for an empty name, but got nil")
+	}
+	if _, err := types.NewTeam(strings.Repeat("a", types.MaxTeamNameLength+1)); err == nil {
+		t.Errorf("Expected an error for a long name, but got nil")
+	}
+}
+
+func TestNewMember(t *testing.T) {
+	member, err := types.NewMember(&types.AddMemberRequest{Email: " ada@example.com ", Role: "editor"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if member.Email != "ada@example.com" || member.Role != types.MemberEditor {
+		t.Errorf("Expected a trimmed email and the editor role, but got %+v", member)
+	}
+
+	if _, err := types.NewMember(&types.AddMemberRequest{Role: "viewer"}); err == nil {
+		t.Errorf("Expected an error for an empty email, but got nil")
+	}
+	if _, err := types.NewMember(&types.AddMemberRequest{Email: "ada@example.com", Role: "owner"}); err == nil {
+		t.Errorf("Expected an error for an unknown role, but got nil")
+	}
+}
+
+func TestNewShareToken(t *testing.T) {
+	first, err := types.NewShareToken()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	second, err := types.NewShareToken()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if len(first) != 64 {
+		t.Errorf("Expected a token of 64 characters, but got %d", len(first))
+	}
+	if first == second {
+		t.Errorf("Expected two different tokens, but got %q twice", first)
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go_api/types"
+)
+
+func TestMentions(t *testing.T) {
+	handles := types.Mentions("@Ada.Lovelace and @grace.hopper, see mail@example.com. Thanks @ada.lovelace.")
+	expected := []string{"ada.lovelace", "grace.hopper"}
+	if strings.Join(handles, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, but got %v", expected, handles)
+	}
+
+	if handles := types.Mentions("no mentions here"); len(handles) != 0 {
+		t.Errorf("Expected no mentions, but got %v", handles)
+	}
+}
+
+func TestUserHandle(t *testing.T) {
+	user := &types.User{FirstName: "Anne-Marie", LastName: "O'Neil Smith"}
+	if handle := user.Handle(); handle != "annemarie.oneilsmith" {
+		t.Errorf("Expected annemarie.oneilsmith, but got %q", handle)
+	}
+
+	mentioned := types.Mentions("thanks @" + user.Handle())
+	if len(mentioned) != 1 || mentioned[0] != user.Handle() {
+		t.Errorf("Expected the handle to be mentionable, but got %v", mentioned)
+	}
+}
+
+func TestNewNotificationPreferences(t *testing.T) {
+	prefs := types.NewNotificationPreferences(&types.UpdateNotificationPreferencesRequest{
+		InApp: types.StringList{"mention", "assigned"},
+		Email: types.StringList{"due_soon", "unknown"},
+	})
+	if len(prefs) != len(types.NotificationKinds) {
+		t.Fatalf("Expected one preference per kind, but got %d", len(prefs))
+	}
+
+	for _, pref := range prefs {
+		wantInApp := pref.Kind != types.NotifyDueSoon
+		wantEmail := pref.Kind == types.NotifyDueSoon
+		if pref.InApp != wantInApp || pref.Email != wantEmail {
+			t.Errorf("Expected %s to be in app %v and email %v, but got %+v", pref.Kind, wantInApp, wantEmail, pref)
+		}
+	}
+}
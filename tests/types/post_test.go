@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	postType "go_api/types"
+)
+
+func TestNewPostIsDraft(t *testing.T) {
+	post, _ := postType.NewPost("Title", "Content", "<p>Content</p>", nil)
+
+	if post.Status != postType.PostDraft {
+		t.Errorf("Expected Status to be %s, but got %s", postType.PostDraft, post.Status)
+	}
+
+	if post.PublishedAt != nil {
+		t.Error("Expected PublishedAt to be nil for a draft")
+	}
+}
+
+func TestSetStatusScheduled(t *testing.T) {
+	now := time.Date(2023, 11, 16, 12, 0, 0, 0, time.UTC)
+	post, _ := postType.NewPost("Title", "Content", "", nil)
+
+	past := now.Add(-time.Hour)
+	if err := post.SetStatus(postType.PostScheduled, &past, now); err == nil {
+		t.Error("Expected scheduling in the past to fail")
+	}
+
+	future := now.Add(time.Hour)
+	if err := post.SetStatus(postType.PostScheduled, &future, now); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if post.IsPublic(now) {
+		t.Error("Expected a scheduled post to be hidden before its publish time")
+	}
+
+	if !post.IsPublic(future) {
+		t.Error("Expected a scheduled post to be public at its publish time")
+	}
+}
+
+func TestSetStatusPublishedKeepsPublishDate(t *testing.T) {
+	now := time.Date(2023, 11, 16, 12, 0, 0, 0, time.UTC)
+	post, _ := postType.NewPost("Title", "Content", "", nil)
+
+	post.SetStatus(postType.PostPublished, nil, now)
+	if post.PublishedAt == nil || !post.PublishedAt.Equal(now) {
+		t.Fatalf("Expected PublishedAt to be %v, but got %v", now, post.PublishedAt)
+	}
+
+	post.SetStatus(postType.PostArchived, nil, now.Add(time.Hour))
+	post.SetStatus(postType.PostPublished, nil, now.Add(2*time.Hour))
+	if !post.PublishedAt.Equal(now) {
+		t.Errorf("Expected PublishedAt to stay %v, but got %v", now, post.PublishedAt)
+	}
+
+	if err := post.SetStatus("deleted", nil, now); err == nil {
+		t.Error("Expected an unknown status to fail")
+	}
+}
+
+func TestPostSlug(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!":            "hello-world",
+		"  Go 1.21 -- what's new ": "go-1-21-what-s-new",
+		"Ünïcode título":           "ünïcode-título",
+		"2023":                     "post-2023",
+		"Tags":                     "post-tags",
+		"!!!":                      "post",
+	}
+
+	for name, expected := range cases {
+		if got := postType.PostSlug(name); got != expected {
+			t.Errorf("Expected slug for %q to be %s, but got %s", name, expected, got)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags := postType.ParseTags("Go, go ,  , Web Dev")
+
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, but got %d", len(tags))
+	}
+
+	if tags[0].Name != "Go" || tags[0].Slug != "go" {
+		t.Errorf("Expected first tag to be Go/go, but got %s/%s", tags[0].Name, tags[0].Slug)
+	}
+
+	if tags[1].Slug != "web-dev" {
+		t.Errorf("Expected second tag slug to be web-dev, but got %s", tags[1].Slug)
+	}
+}
+
+func TestUserCanEditPost(t *testing.T) {
+	ownID, otherID := 1, 2
+	own := &postType.Post{UserID: &ownID}
+	other := &postType.Post{UserID: &otherID}
+	orphan := &postType.Post{}
+
+	tests := []struct {
+		role     string
+		owner    string
+		post     *postType.Post
+		canWrite bool
+		canEdit  bool
+	}{
+		{postType.RoleAdmin, "someone else", other, true, true},
+		{postType.RoleAdmin, "nobody", orphan, true, true},
+		{postType.RoleAuthor, "them", own, true, true},
+		{postType.RoleAuthor, "someone else", other, true, false},
+		{postType.RoleAuthor, "nobody", orphan, true, false},
+		{postType.RoleUser, "them", own, false, false},
+	}
+
+	for _, test := range tests {
+		user := &postType.User{ID: ownID, Role: postType.Role{Name: test.role}}
+		if canWrite := user.CanWritePosts(); canWrite != test.canWrite {
+			t.Errorf("Expected %s writing posts to be %v, but got %v", test.role, test.canWrite, canWrite)
+		}
+		if canEdit := user.CanEditPost(test.post); canEdit != test.canEdit {
+			t.Errorf("Expected %s editing a post by %s to be %v, but got %v", test.role, test.owner, test.canEdit, canEdit)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package tests
+
+import (
+	"testing"
+
+	searchType "go_api/types"
+)
+
+func TestSearchResultSnippetHTML(t *testing.T) {
+	result := &searchType.SearchResult{
+		Snippet: "use <script> with " + searchType.SearchMatchStart + "goroutines" + searchType.SearchMatchStop + " & channels",
+	}
+
+	expected := "use &lt;script&gt; with <mark>goroutines</mark> &amp; channels"
+	if got := string(result.SnippetHTML()); got != expected {
+		t.Errorf("Expected snippet %q, but got %q", expected, got)
+	}
+}
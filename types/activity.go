@@ -0,0 +1,64 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CardEventKind string
+
+const (
+	EventCreated  CardEventKind = "created"
+	EventMoved    CardEventKind = "moved"
+	EventRenamed  CardEventKind = "renamed"
+	EventAssigned CardEventKind = "assigned"
+	EventComment  CardEventKind = "comment"
+)
+
+type CreateCardCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// CardEvent is one entry in a card's activity stream. From and To hold the
+// column names of a move, the old and new name of a rename, and the new
+// assignee of an assignment (empty when the card was unassigned). Body is
+// only set on comments.
+type CardEvent struct {
+	ID        int           `json:"id"`
+	CardID    int           `json:"cardId"`
+	UserID    *int          `json:"userId"`
+	Author    string        `json:"author"`
+	Kind      CardEventKind `json:"kind"`
+	From      string        `json:"from,omitempty"`
+	To        string        `json:"to,omitempty"`
+	Body      string        `json:"body,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+func NewCardEvent(cardID int, userID *int, kind CardEventKind, from, to string) *CardEvent {
+	return &CardEvent{
+		CardID:    cardID,
+		UserID:    userID,
+		Kind:      kind,
+		From:      from,
+		To:        to,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func NewCardComment(cardID int, userID int, body string) (*CardEvent, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("Comment cannot be empty.")
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return nil, fmt.Errorf("Comments must be at most %d characters.", MaxCommentLength)
+	}
+
+	event := NewCardEvent(cardID, &userID, EventComment, "", "")
+	event.Body = body
+	return event, nil
+}
@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// PostViews is the number of unique daily visitors of a post.
+type PostViews struct {
+	PostID int       `json:"postId"`
+	Day    time.Time `json:"day"`
+	Views  int       `json:"views"`
+}
+
+// PostViewStats is a post's total views over a period.
+type PostViewStats struct {
+	PostID int    `json:"postId"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Views  int    `json:"views"`
+}
+
+type DailyViews struct {
+	Day   time.Time `json:"day"`
+	Views int       `json:"views"`
+}
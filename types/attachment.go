@@ -0,0 +1,75 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+type AttachmentKind string
+
+const (
+	AttachmentPost AttachmentKind = "post"
+	AttachmentCard AttachmentKind = "card"
+)
+
+type AttachmentLimits struct {
+	MaxSize      int64
+	ContentTypes []string
+}
+
+// AttachmentRules holds the size and sniffed content type limits for each
+// kind of attachment.
+var AttachmentRules = map[AttachmentKind]AttachmentLimits{
+	AttachmentPost: {
+		MaxSize:      5 << 20,
+		ContentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"},
+	},
+	AttachmentCard: {
+		MaxSize:      20 << 20,
+		ContentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain", "application/zip"},
+	},
+}
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	PostID      *int      `json:"postId"`
+	CardID      *int      `json:"cardId"`
+	Key         string    `json:"key"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+	URL         string    `json:"url"`
+}
+
+func NewAttachment(kind AttachmentKind, ownerID int, key, fileName, contentType string, size int64) *Attachment {
+	attachment := &Attachment{
+		Key:         key,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if kind == AttachmentPost {
+		attachment.PostID = &ownerID
+	} else {
+		attachment.CardID = &ownerID
+	}
+	return attachment
+}
+
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// Allows reports whether a sniffed content type such as
+// "text/plain; charset=utf-8" is accepted.
+func (l AttachmentLimits) Allows(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, allowed := range l.ContentTypes {
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
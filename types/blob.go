@@ -0,0 +1,29 @@
+package types
+
+import (
+	"time"
+)
+
+// Blob records a file in the blob store and how many rows point at it.
+// Blobs with no references are removed by the garbage collector once they
+// are older than its grace period.
+type Blob struct {
+	Key         string    `json:"key"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	RefCount    int       `json:"refCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func NewBlob(key, sha256 string, size int64, contentType string) *Blob {
+	return &Blob{
+		Key:         key,
+		SHA256:      sha256,
+		Size:        size,
+		ContentType: contentType,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+}
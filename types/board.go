@@ -0,0 +1,160 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxBoardNameLength  = 80
+	MaxColumnNameLength = 40
+)
+
+// DefaultColumns are created with every new board.
+var DefaultColumns = []string{"To do", "In progress", "Done"}
+
+// CreateBoardRequest creates a board owned by its creator, or by the team
+// TeamID when one is chosen.
+type CreateBoardRequest struct {
+	Name   string `json:"name"`
+	TeamID string `json:"teamId"`
+}
+
+type CreateColumnRequest struct {
+	Name string `json:"name"`
+}
+
+type Board struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	OwnerID   *int      `json:"ownerId"`
+	TeamID    *int      `json:"teamId"`
+	Columns   []*Column `json:"columns"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// ShareToken opens the board read-only to anyone with its link; empty
+	// while the board is not shared.
+	ShareToken string `json:"-"`
+	// Role is the role of the user the board was listed for.
+	Role MemberRole `json:"role,omitempty"`
+}
+
+type Column struct {
+	ID        int       `json:"id"`
+	BoardID   int       `json:"boardId"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	Cards     []*Card   `json:"cards"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// MoveCardRequest describes one drag: the card lands in ColumnID between
+// the cards AfterID and BeforeID. Version is the board version the browser
+// rendered, so a move based on a stale view can be refused.
+type MoveCardRequest struct {
+	CardID   int
+	ColumnID int
+	AfterID  *int
+	BeforeID *int
+	Version  int
+}
+
+func NewBoard(name string) (*Board, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("Board name cannot be empty.")
+	}
+	if utf8.RuneCountInString(name) > MaxBoardNameLength {
+		return nil, fmt.Errorf("Board names must be at most %d characters.", MaxBoardNameLength)
+	}
+
+	board := &Board{
+		Name:      name,
+		Columns:   []*Column{},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	for i, columnName := range DefaultColumns {
+		column, err := NewColumn(0, columnName, i)
+		if err != nil {
+			return nil, err
+		}
+		board.Columns = append(board.Columns, column)
+	}
+	return board, nil
+}
+
+func NewColumn(boardID int, name string, position int) (*Column, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("Column name cannot be empty.")
+	}
+	if utf8.RuneCountInString(name) > MaxColumnNameLength {
+		return nil, fmt.Errorf("Column names must be at most %d characters.", MaxColumnNameLength)
+	}
+
+	return &Column{
+		BoardID:   boardID,
+		Name:      name,
+		Position:  position,
+		Cards:     []*Card{},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Column returns the board's column with the given id, or nil.
+func (b *Board) Column(id int) *Column {
+	for _, column := range b.Columns {
+		if column.ID == id {
+			return column
+		}
+	}
+	return nil
+}
+
+// ParseMoveCardRequest reads the fields the board form posts after a drag.
+// The neighbour ids are optional: a card dropped at the top of a column has
+// no card after it, one dropped at the bottom no card before it.
+func ParseMoveCardRequest(form url.Values) (*MoveCardRequest, error) {
+	move := new(MoveCardRequest)
+	var err error
+	if move.CardID, err = strconv.Atoi(form.Get("card")); err != nil {
+		return nil, fmt.Errorf("invalid card id %q", form.Get("card"))
+	}
+	if move.ColumnID, err = strconv.Atoi(form.Get("column")); err != nil {
+		return nil, fmt.Errorf("invalid column id %q", form.Get("column"))
+	}
+	if move.Version, err = strconv.Atoi(form.Get("version")); err != nil {
+		return nil, fmt.Errorf("invalid board version %q", form.Get("version"))
+	}
+	if move.AfterID, err = optionalID(form.Get("after")); err != nil {
+		return nil, err
+	}
+	if move.BeforeID, err = optionalID(form.Get("before")); err != nil {
+		return nil, err
+	}
+
+	if move.AfterID != nil && *move.AfterID == move.CardID || move.BeforeID != nil && *move.BeforeID == move.CardID {
+		return nil, fmt.Errorf("card %d cannot be its own neighbour", move.CardID)
+	}
+	return move, nil
+}
+
+func optionalID(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid card id %q", value)
+	}
+	return &id, nil
+}
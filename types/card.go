@@ -1,51 +1,228 @@
 package types
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+const MaxCardNameLength = 40
+
+type CardPriority string
+
+const (
+	PriorityNone   CardPriority = "none"
+	PriorityLow    CardPriority = "low"
+	PriorityMedium CardPriority = "medium"
+	PriorityHigh   CardPriority = "high"
+)
+
+var CardPriorities = []CardPriority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh}
+
+func (p CardPriority) Valid() bool {
+	for _, priority := range CardPriorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// StringList decodes a JSON string or array of strings. Forms sent with the
+// json-enc extension post a single checked checkbox as a plain string and
+// several as an array.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+type CreateCardRequest struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
 type UpdateCardRequest struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Content  string `json:"content"`
-	Position int    `json:"position"`
-	Parent   *int   `json:"cards"`
+	Name       string     `json:"name"`
+	Content    string     `json:"content"`
+	AssigneeID string     `json:"assigneeId"`
+	DueOn      string     `json:"dueOn"`
+	Priority   string     `json:"priority"`
+	Labels     StringList `json:"labels"`
 }
 
 type Card struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Content   string    `json:"content"`
-	Position  int       `json:"position"`
-	CardType  bool      `json:"cardType"`
-	Parent    *int      `json:"cards"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-}
-type UpdateCardPositions struct {
-	Items []int `json:"items"`
+	ID         int          `json:"id"`
+	ColumnID   int          `json:"columnId"`
+	BoardID    int          `json:"boardId"`
+	Name       string       `json:"name"`
+	Content    string       `json:"content"`
+	Rank       string       `json:"rank"`
+	Parent     *int         `json:"cards"`
+	Done       bool         `json:"done"`
+	AssigneeID *int         `json:"assigneeId"`
+	DueOn      *time.Time   `json:"dueOn"`
+	Priority   CardPriority `json:"priority"`
+	Labels     []*Label     `json:"labels"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  time.Time    `json:"updatedAt"`
+	// Assignee is the assigned user's full name, for display.
+	Assignee string `json:"assignee,omitempty"`
+	// Children are the sub-cards, used as checklist items, in rank order.
+	Children []*Card `json:"children,omitempty"`
 }
 
-func NewCard(name string, content string, position int, cardType bool, parent *int) (*Card, error) {
+func NewCard(columnID int, name string, content string, parent *int) (*Card, error) {
+	name, err := cardName(name)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Card{
+		ColumnID:  columnID,
 		Name:      name,
-		Content:   content,
-		Position:  position,
-		CardType:  cardType,
+		Content:   strings.TrimSpace(content),
 		Parent:    parent,
+		Priority:  PriorityNone,
+		Labels:    []*Label{},
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}, nil
 }
 
-func UpdateCard(id int, name, content string, position int, parent *int) *Card {
+func UpdateCard(id int, name, content string) (*Card, error) {
+	name, err := cardName(name)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Card{
 		ID:        id,
 		Name:      name,
-		Content:   content,
-		Position:  position,
-		Parent:    parent,
+		Content:   strings.TrimSpace(content),
 		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// IsOverdue reports whether the card is still open after its due date has
+// passed. A card due today is not overdue yet.
+func (c *Card) IsOverdue(now time.Time) bool {
+	if c.DueOn == nil || c.Done {
+		return false
+	}
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return c.DueOn.Before(today)
+}
+
+// Overdue is IsOverdue for the current time, for templates.
+func (c *Card) Overdue() bool {
+	return c.IsOverdue(time.Now().UTC())
+}
+
+func (c *Card) AssignedTo(userID int) bool {
+	return c.AssigneeID != nil && *c.AssigneeID == userID
+}
+
+func (c *Card) HasLabel(id int) bool {
+	for _, label := range c.Labels {
+		if label.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AssigneeInitials are shown on the board in place of the full name.
+func (c *Card) AssigneeInitials() string {
+	initials := []rune{}
+	for _, word := range strings.Fields(c.Assignee) {
+		initials = append(initials, []rune(word)[0])
+		if len(initials) == 2 {
+			break
+		}
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// NestCards hangs every card under its parent and returns the cards whose
+// parent is not in the list, keeping the input order at every level.
+func NestCards(cards []*Card) []*Card {
+	byID := make(map[int]*Card, len(cards))
+	for _, card := range cards {
+		card.Children = []*Card{}
+		byID[card.ID] = card
+	}
+
+	roots := []*Card{}
+	for _, card := range cards {
+		if card.Parent != nil {
+			if parent, ok := byID[*card.Parent]; ok {
+				parent.Children = append(parent.Children, card)
+				continue
+			}
+		}
+		roots = append(roots, card)
+	}
+	return roots
+}
+
+// ItemCount is the number of checklist items below the card, at any depth.
+func (c *Card) ItemCount() int {
+	count := 0
+	for _, child := range c.Children {
+		count += 1 + child.ItemCount()
+	}
+	return count
+}
+
+// DoneCount is the number of checklist items below the card that are done.
+func (c *Card) DoneCount() int {
+	count := 0
+	for _, child := range c.Children {
+		if child.Done {
+			count++
+		}
+		count += child.DoneCount()
+	}
+	return count
+}
+
+// Contains reports whether the card with the given id is this card or one
+// of its descendants.
+func (c *Card) Contains(id int) bool {
+	if c.ID == id {
+		return true
+	}
+	for _, child := range c.Children {
+		if child.Contains(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func cardName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Card title cannot be empty.")
+	}
+	if utf8.RuneCountInString(name) > MaxCardNameLength {
+		return "", fmt.Errorf("Card titles must be at most %d characters.", MaxCardNameLength)
 	}
+	return name, nil
 }
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxCommentLength = 5000
+
+type CommentStatus string
+
+const (
+	CommentPending  CommentStatus = "pending"
+	CommentApproved CommentStatus = "approved"
+	CommentRejected CommentStatus = "rejected"
+)
+
+type CreateCommentRequest struct {
+	Body     string `json:"body"`
+	ParentID string `json:"parentId"`
+}
+
+// Comment is a visitor's comment on a post. Replies hang off a top-level
+// comment through ParentID; there is only one level of nesting.
+type Comment struct {
+	ID        int           `json:"id"`
+	PostID    int           `json:"postId"`
+	ParentID  *int          `json:"parentId"`
+	UserID    int           `json:"userId"`
+	Author    string        `json:"author"`
+	Body      string        `json:"body"`
+	Status    CommentStatus `json:"status"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	// PostName and PostSlug are filled in for the moderation queue.
+	PostName string     `json:"postName,omitempty"`
+	PostSlug string     `json:"postSlug,omitempty"`
+	Replies  []*Comment `json:"replies,omitempty"`
+}
+
+func NewComment(postID int, parentID *int, userID int, body string, status CommentStatus) (*Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("Comment cannot be empty.")
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return nil, fmt.Errorf("Comments must be at most %d characters.", MaxCommentLength)
+	}
+
+	return &Comment{
+		PostID:    postID,
+		ParentID:  parentID,
+		UserID:    userID,
+		Body:      body,
+		Status:    status,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// ThreadComments nests replies under their top-level comment, keeping the
+// input order. Replies whose parent is missing, e.g. still awaiting
+// moderation, are dropped.
+func ThreadComments(comments []*Comment) []*Comment {
+	threads := []*Comment{}
+	byID := map[int]*Comment{}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			comment.Replies = []*Comment{}
+			byID[comment.ID] = comment
+			threads = append(threads, comment)
+		}
+	}
+
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		}
+	}
+
+	return threads
+}
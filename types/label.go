@@ -0,0 +1,57 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const MaxLabelNameLength = 30
+
+// LabelColors is the palette offered when creating a label.
+var LabelColors = []string{"#61bd4f", "#f2d600", "#ff9f1a", "#eb5a46", "#c377e0", "#0079bf", "#00c2e0", "#344563"}
+
+type CreateLabelRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Label is a colored tag that belongs to one board and can be put on any of
+// its cards.
+type Label struct {
+	ID      int    `json:"id"`
+	BoardID int    `json:"boardId"`
+	Name    string `json:"name"`
+	Color   string `json:"color"`
+}
+
+func NewLabel(boardID int, name, color string) (*Label, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("Label name cannot be empty.")
+	}
+	if utf8.RuneCountInString(name) > MaxLabelNameLength {
+		return nil, fmt.Errorf("Label names must be at most %d characters.", MaxLabelNameLength)
+	}
+
+	color = strings.ToLower(strings.TrimSpace(color))
+	if !validColor(color) {
+		return nil, fmt.Errorf("invalid label color %q", color)
+	}
+
+	return &Label{BoardID: boardID, Name: name, Color: color}, nil
+}
+
+// validColor accepts colors written as #rrggbb.
+func validColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	for _, r := range color[1:] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
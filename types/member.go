@@ -0,0 +1,112 @@
+package types
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxTeamNameLength = 80
+
+// MemberRole is what someone may do on a board, or on every board of a team.
+type MemberRole string
+
+const (
+	MemberViewer MemberRole = "viewer"
+	MemberEditor MemberRole = "editor"
+	MemberAdmin  MemberRole = "admin"
+)
+
+// MemberRoles lists the roles from the weakest to the strongest.
+var MemberRoles = []MemberRole{MemberViewer, MemberEditor, MemberAdmin}
+
+type CreateTeamRequest struct {
+	Name string `json:"name"`
+}
+
+// AddMemberRequest adds someone to a board or a team by the email they
+// registered with, or changes the role they already have.
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type Team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Role is the role of the user the team was loaded for.
+	Role      MemberRole `json:"role"`
+	Members   []*Member  `json:"members"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Member is a user with access to a board or a team. Removable is false for
+// board members who only get their access from owning the board or from its
+// team.
+type Member struct {
+	UserID    int        `json:"userId"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Role      MemberRole `json:"role"`
+	Removable bool       `json:"removable"`
+}
+
+func (r MemberRole) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether the role grants at least the access of required.
+// The empty role, no access at all, allows nothing.
+func (r MemberRole) Allows(required MemberRole) bool {
+	return r.rank() > 0 && r.rank() >= required.rank()
+}
+
+func (r MemberRole) rank() int {
+	for i, role := range MemberRoles {
+		if role == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func NewTeam(name string) (*Team, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("Team name cannot be empty.")
+	}
+	if utf8.RuneCountInString(name) > MaxTeamNameLength {
+		return nil, fmt.Errorf("Team names must be at most %d characters.", MaxTeamNameLength)
+	}
+
+	return &Team{Name: name, Members: []*Member{}, CreatedAt: time.Now().UTC()}, nil
+}
+
+// NewMember validates an add member request. The user behind the email is
+// looked up by the caller.
+func NewMember(req *AddMemberRequest) (*Member, error) {
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return nil, errors.New("Email cannot be empty.")
+	}
+
+	role := MemberRole(req.Role)
+	if !role.Valid() {
+		return nil, fmt.Errorf("invalid role %q", req.Role)
+	}
+
+	return &Member{Email: email, Role: role}, nil
+}
+
+// NewShareToken returns the secret part of a board's public link.
+func NewShareToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,128 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type NotificationKind string
+
+const (
+	NotifyDueSoon  NotificationKind = "due_soon"
+	NotifyMention  NotificationKind = "mention"
+	NotifyAssigned NotificationKind = "assigned"
+)
+
+// NotificationKinds lists the kinds in the order the preferences show them.
+var NotificationKinds = []NotificationKind{NotifyDueSoon, NotifyMention, NotifyAssigned}
+
+func (k NotificationKind) Valid() bool {
+	for _, kind := range NotificationKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (k NotificationKind) Label() string {
+	switch k {
+	case NotifyDueSoon:
+		return "Cards due soon"
+	case NotifyMention:
+		return "Mentions"
+	case NotifyAssigned:
+		return "Assignments"
+	}
+	return string(k)
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	InApp StringList `json:"inApp"`
+	Email StringList `json:"email"`
+}
+
+// Notification tells a user about something that concerns them. InApp and
+// Email are the channels it goes out on, taken from the user's preferences
+// when it is created.
+type Notification struct {
+	ID     int              `json:"id"`
+	UserID int              `json:"userId"`
+	Kind   NotificationKind `json:"kind"`
+	CardID *int             `json:"cardId"`
+	// ActorID is the user whose action caused the notification, if any.
+	ActorID   *int       `json:"actorId"`
+	Message   string     `json:"message"`
+	URL       string     `json:"url"`
+	InApp     bool       `json:"inApp"`
+	Email     bool       `json:"email"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt"`
+	// DedupeKey makes a notification that was already created for its user
+	// be skipped, e.g. a second reminder for the same due date.
+	DedupeKey string `json:"-"`
+	// Recipient is the user's email address, filled in for delivery.
+	Recipient string `json:"-"`
+}
+
+// NotificationPreference says how a user wants to hear about one kind of
+// notification. Without a stored preference they only get it in the app.
+type NotificationPreference struct {
+	Kind  NotificationKind `json:"kind"`
+	InApp bool             `json:"inApp"`
+	Email bool             `json:"email"`
+}
+
+func NewNotification(userID int, kind NotificationKind, message string, url string) *Notification {
+	return &Notification{
+		UserID:    userID,
+		Kind:      kind,
+		Message:   message,
+		URL:       url,
+		InApp:     true,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func (n *Notification) Unread() bool {
+	return n.ReadAt == nil
+}
+
+// NewNotificationPreferences turns the kinds ticked in the preferences form
+// into one preference per kind.
+func NewNotificationPreferences(req *UpdateNotificationPreferencesRequest) []*NotificationPreference {
+	prefs := []*NotificationPreference{}
+	for _, kind := range NotificationKinds {
+		prefs = append(prefs, &NotificationPreference{
+			Kind:  kind,
+			InApp: contains(req.InApp, string(kind)),
+			Email: contains(req.Email, string(kind)),
+		})
+	}
+	return prefs
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\pL\pN_.@])@([\pL\pN]+(?:\.[\pL\pN]+)*)`)
+
+// Mentions returns the handles mentioned as @first.last in text, lowercased
+// and without duplicates.
+func Mentions(text string) []string {
+	handles := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(match[1])
+		if !contains(handles, handle) {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
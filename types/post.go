@@ -1,40 +1,150 @@
 package types
 
 import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
 	"time"
 )
 
-type UpdatePostRequest struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
+type PostStatus string
+
+const (
+	PostDraft     PostStatus = "draft"
+	PostScheduled PostStatus = "scheduled"
+	PostPublished PostStatus = "published"
+	PostArchived  PostStatus = "archived"
+)
+
+var PostStatuses = []PostStatus{PostDraft, PostScheduled, PostPublished, PostArchived}
+
+func (s PostStatus) Valid() bool {
+	for _, status := range PostStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+type CreatePostRequest struct {
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+	Status    string `json:"status"`
+	PublishAt string `json:"publishAt"`
+	Category  string `json:"category"`
+	Tags      string `json:"tags"`
+}
+
+type PreviewPostRequest struct {
 	Content string `json:"content"`
 }
 
+type UpdatePostRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+	Status    string `json:"status"`
+	PublishAt string `json:"publishAt"`
+	Category  string `json:"category"`
+	Tags      string `json:"tags"`
+}
+
+// Post.Content holds the Markdown source and ContentHTML the sanitized HTML
+// rendered from it when the post is saved.
 type Post struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Content   string    `json:"content"`
-	ImageURL  *string   `json:"image_url"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Content     string     `json:"content"`
+	ContentHTML string     `json:"contentHtml"`
+	Excerpt     string     `json:"excerpt"`
+	ImageURL    *string    `json:"image_url"`
+	Status      PostStatus `json:"status"`
+	PublishedAt *time.Time `json:"publishedAt"`
+	Category    *Category  `json:"category"`
+	Tags        []*Tag     `json:"tags"`
+	UserID      *int       `json:"userId"`
+	Author      string     `json:"author"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
-func NewPost(name, content string, imageUrl *string) (*Post, error) {
+func NewPost(name, content, contentHTML string, userID *int) (*Post, error) {
 	return &Post{
-		Name:      name,
-		Content:   content,
-		ImageURL:  imageUrl,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+		Name:        name,
+		Content:     content,
+		ContentHTML: contentHTML,
+		Status:      PostDraft,
+		UserID:      userID,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
 	}, nil
 }
 
-func UpdatePost(id int, name, content string) *Post {
+func UpdatePost(id int, name, content, contentHTML string) *Post {
 
 	return &Post{
-		ID:        id,
-		Name:      name,
-		Content:   content,
-		UpdatedAt: time.Now().UTC(),
+		ID:          id,
+		Name:        name,
+		Content:     content,
+		ContentHTML: contentHTML,
+		UpdatedAt:   time.Now().UTC(),
 	}
 }
+
+// TagList joins the tag names the way the editor accepts them.
+func (p *Post) TagList() string {
+	names := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// HTML marks the stored rendering as safe for templates. ContentHTML is
+// only ever written after passing through the sanitizer.
+func (p *Post) HTML() template.HTML {
+	return template.HTML(p.ContentHTML)
+}
+
+// SetStatus moves the post through the publishing workflow. Scheduled posts
+// need a publish time in the future; publishing keeps an existing publish
+// date so re-publishing an archived post doesn't bump it.
+func (p *Post) SetStatus(status PostStatus, publishAt *time.Time, now time.Time) error {
+	switch status {
+	case PostDraft:
+		p.PublishedAt = nil
+	case PostScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return errors.New("Scheduled posts need a publish time in the future.")
+		}
+		p.PublishedAt = publishAt
+	case PostPublished:
+		if p.PublishedAt == nil || p.PublishedAt.After(now) {
+			p.PublishedAt = &now
+		}
+	case PostArchived:
+	default:
+		return fmt.Errorf("Unknown status %q.", status)
+	}
+
+	p.Status = status
+	return nil
+}
+
+// SortTime is the time listings order the post by: when it was published,
+// or when it was created for posts that never were.
+func (p *Post) SortTime() time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+// IsPublic reports whether visitors can see the post.
+func (p *Post) IsPublic(now time.Time) bool {
+	return p.Status == PostPublished ||
+		(p.Status == PostScheduled && p.PublishedAt != nil && !p.PublishedAt.After(now))
+}
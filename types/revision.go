@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// PostRevision is a snapshot of a post's title and Markdown source, taken
+// every time the post is saved.
+type PostRevision struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"postId"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	UserID    *int      `json:"userId"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewPostRevision(post *Post, userID *int) *PostRevision {
+	return &PostRevision{
+		PostID:    post.ID,
+		Name:      post.Name,
+		Content:   post.Content,
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
@@ -1,5 +1,11 @@
 package types
 
+const (
+	RoleAdmin  = "admin"
+	RoleAuthor = "author"
+	RoleUser   = "user"
+)
+
 type Role struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
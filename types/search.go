@@ -0,0 +1,38 @@
+package types
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Search snippets come back from the database with matches wrapped in these
+// private-use runes, so the text can be escaped before marking them up.
+const (
+	SearchMatchStart = "\uE000"
+	SearchMatchStop  = "\uE001"
+)
+
+type SearchKind string
+
+const (
+	SearchPost SearchKind = "post"
+)
+
+// SearchResult is one hit of a full-text search. It is shared by every kind
+// of searchable content so results can be listed together.
+type SearchResult struct {
+	Kind    SearchKind `json:"kind"`
+	ID      int        `json:"id"`
+	Title   string     `json:"title"`
+	URL     string     `json:"url"`
+	Snippet string     `json:"snippet"`
+	Rank    float64    `json:"rank"`
+}
+
+// SnippetHTML escapes the snippet and highlights the matched words.
+func (r *SearchResult) SnippetHTML() template.HTML {
+	escaped := template.HTMLEscapeString(r.Snippet)
+	escaped = strings.ReplaceAll(escaped, SearchMatchStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, SearchMatchStop, "</mark>")
+	return template.HTML(escaped)
+}
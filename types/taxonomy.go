@@ -0,0 +1,121 @@
+package types
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSlugLength         = 80
+	maxTaxonomyNameLength = 100
+)
+
+// reservedSlugs are path segments under /posts that a post slug must not
+// shadow. The slug backfill in 20231117120000_create_table_post_taxonomy
+// lists them too; reserving another one needs a migration that renames the
+// posts already using it.
+var reservedSlugs = map[string]bool{
+	"new":        true,
+	"preview":    true,
+	"tags":       true,
+	"categories": true,
+	"analytics":  true,
+}
+
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type Category struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// PostFilter narrows a post listing to one tag or category, by slug.
+type PostFilter struct {
+	Tag      string
+	Category string
+}
+
+func NewTag(name string) *Tag {
+	name = taxonomyName(name)
+	return &Tag{Name: name, Slug: Slugify(name)}
+}
+
+func NewCategory(name string) *Category {
+	name = taxonomyName(name)
+	return &Category{Name: name, Slug: Slugify(name)}
+}
+
+func taxonomyName(name string) string {
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > maxTaxonomyNameLength {
+		name = strings.TrimSpace(string(runes[:maxTaxonomyNameLength]))
+	}
+	return name
+}
+
+// ParseTags splits a comma separated list of tag names, dropping blanks and
+// names that map to the same slug.
+func ParseTags(list string) []*Tag {
+	tags := []*Tag{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		tag := NewTag(name)
+		if tag.Slug == "" || seen[tag.Slug] {
+			continue
+		}
+		seen[tag.Slug] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Slugify turns a title into a lowercase, dash separated URL segment.
+// Letters and digits are kept, everything else collapses into single dashes.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if runes := []rune(slug); len(runes) > maxSlugLength {
+		slug = strings.TrimRight(string(runes[:maxSlugLength]), "-")
+	}
+	return slug
+}
+
+// PostSlug is Slugify for post titles. Numeric slugs would be read as post
+// ids and reserved ones would shadow other routes, so both get a prefix.
+func PostSlug(name string) string {
+	slug := Slugify(name)
+	if slug == "" {
+		return "post"
+	}
+	if reservedSlugs[slug] || isNumeric(slug) {
+		return "post-" + slug
+	}
+	return slug
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Upload tracks a resumable upload while its chunks are being received.
+// Offset is persisted after every chunk so an interrupted client can ask
+// where to continue from.
+type Upload struct {
+	ID        string    `json:"id"`
+	CardID    int       `json:"cardId"`
+	FileName  string    `json:"fileName"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewUpload(cardID int, fileName string, size int64, checksum string) (*Upload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &Upload{
+		ID:        hex.EncodeToString(id),
+		CardID:    cardID,
+		FileName:  fileName,
+		Size:      size,
+		Checksum:  checksum,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Size
+}
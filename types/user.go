@@ -1,7 +1,9 @@
 package types
 
 import (
+	"strings"
 	"time"
+	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -13,7 +15,7 @@ type UpdateUserRequest struct {
 }
 type UpdateUserImageRequest struct {
 	ID       int    `json:"id"`
-	ImageURL string `json:"image_url"`
+	ImageKey string `json:"imageKey"`
 }
 type User struct {
 	ID        int       `json:"id"`
@@ -24,7 +26,10 @@ type User struct {
 	Role      Role      `json:"role"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
-	ImageURL  string    `json:"imageURL"`
+	ImageKey  string    `json:"imageKey"`
+	// ImageURLs maps each avatar variant name to its public URL. It is
+	// resolved from ImageKey by the handlers through the blob store.
+	ImageURLs map[string]string `json:"imageURLs"`
 }
 
 func NewUser(firstName, lastName, email, password string) (*User, error) {
@@ -51,6 +56,49 @@ func UpdateUser(id int, firstName, lastName string) *User {
 	}
 }
 
+// CanWritePosts reports whether the user may create blog posts.
+func (u *User) CanWritePosts() bool {
+	return u.Role.Name == RoleAdmin || u.Role.Name == RoleAuthor
+}
+
+// CanEditPost reports whether the user may change or delete a post. Admins
+// can edit every post, authors only their own.
+func (u *User) CanEditPost(post *Post) bool {
+	if u.Role.Name == RoleAdmin {
+		return true
+	}
+	return u.Role.Name == RoleAuthor && post.UserID != nil && *post.UserID == u.ID
+}
+
+// CanModerateComments reports whether the user may approve, reject and
+// delete other people's comments.
+func (u *User) CanModerateComments() bool {
+	return u.Role.Name == RoleAdmin
+}
+
+// CanViewAnalytics reports whether the user may see the view statistics.
+func (u *User) CanViewAnalytics() bool {
+	return u.Role.Name == RoleAdmin
+}
+
+// Handle is how the user is mentioned: their first and last name joined by
+// a dot, lowercased and without spaces or punctuation.
+func (u *User) Handle() string {
+	parts := []string{}
+	for _, name := range []string{u.FirstName, u.LastName} {
+		part := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, name)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
 func (u *User) ValidPassword(pw string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(pw)) == nil
 }